@@ -0,0 +1,212 @@
+// Package batch runs one program against many inputs with a pool of workers.
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	interpreter "github.com/momaee/WL"
+)
+
+// Input is one input of a batch.
+type Input struct {
+	Name string
+	Data []byte
+}
+
+// Status tells how a run ended.
+type Status string
+
+const (
+	StatusOK        Status = "ok"
+	StatusError     Status = "error"
+	StatusStepLimit Status = "step-limit"
+	StatusTimeout   Status = "timeout"
+)
+
+// Result is the outcome of running the program with one input.
+// Output is base64 encoded in JSON, as it is made of bytes which need not be valid UTF-8.
+type Result struct {
+	Name     string        `json:"name"`
+	Status   Status        `json:"status"`
+	Error    string        `json:"error,omitempty"`
+	Output   []byte        `json:"output"`
+	Steps    int           `json:"steps"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Runner runs Program with Workers goroutines, 0 means one per CPU.
+// a run fails after MaxSteps instructions or after Timeout, 0 means no limit.
+// Options are added to the options of every interpreter.
+type Runner struct {
+	Program  *interpreter.Program
+	Workers  int
+	MaxSteps int
+	Timeout  time.Duration
+	Options  []interpreter.Option
+}
+
+// Run runs the program with every input and returns the results in the order of the inputs.
+// the inputs which have not started when ctx is cancelled get a timeout result.
+func (r *Runner) Run(ctx context.Context, inputs []Input) []Result {
+	results := make([]Result, len(inputs))
+	_ = r.run(ctx, inputs, func(i int, res Result) error {
+		results[i] = res
+		return nil
+	})
+	return results
+}
+
+// WriteJSON runs the program with every input and writes the results to w as JSON lines,
+// in the order in which they finish.
+func (r *Runner) WriteJSON(ctx context.Context, w io.Writer, inputs []Input) error {
+	enc := json.NewEncoder(w)
+	return r.run(ctx, inputs, func(i int, res Result) error {
+		return enc.Encode(res)
+	})
+}
+
+// run calls done from one goroutine at a time with the index and the result of each input.
+func (r *Runner) run(ctx context.Context, inputs []Input, done func(int, Result) error) error {
+	workers := r.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		err  error
+		jobs = make(chan int)
+	)
+	for n := 0; n < workers; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				res := r.runOne(ctx, inputs[i])
+				mu.Lock()
+				if err == nil {
+					err = done(i, res)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range inputs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return err
+}
+
+// runOne runs the program with one input.
+func (r *Runner) runOne(ctx context.Context, in Input) Result {
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+
+	var out bytes.Buffer
+	opts := append(r.Options[:len(r.Options):len(r.Options)], interpreter.WithContext(ctx), interpreter.WithMaxSteps(r.MaxSteps))
+	bfm := r.Program.NewInterpreter(bytes.NewReader(in.Data), &out, opts...)
+
+	start := time.Now()
+	err := ctx.Err()
+	if err == nil {
+		err = bfm.Run()
+	}
+	res := Result{
+		Name:     in.Name,
+		Status:   StatusOK,
+		Output:   out.Bytes(),
+		Steps:    bfm.Steps(),
+		Duration: time.Since(start),
+	}
+
+	switch {
+	case err == nil || err == io.EOF:
+	case errors.Is(err, interpreter.ErrStepLimit):
+		res.Status = StatusStepLimit
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled):
+		res.Status = StatusTimeout
+	default:
+		res.Status = StatusError
+	}
+	if res.Status != StatusOK {
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// Files reads the inputs from the given files, the name of an input is the path of its file.
+// the regular files of a directory are read in the order of their names.
+func Files(paths ...string) ([]Input, error) {
+	var inputs []Input
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			inputs = append(inputs, Input{Name: path, Data: data})
+			continue
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if !e.Type().IsRegular() {
+				continue
+			}
+			name := filepath.Join(path, e.Name())
+			data, err := os.ReadFile(name)
+			if err != nil {
+				return nil, err
+			}
+			inputs = append(inputs, Input{Name: name, Data: data})
+		}
+	}
+	return inputs, nil
+}
+
+// FS reads the inputs from the regular files of the directory dir of fsys.
+func FS(fsys fs.FS, dir string) ([]Input, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	var inputs []Input
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		name := e.Name()
+		if dir != "." {
+			name = dir + "/" + name
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, Input{Name: name, Data: data})
+	}
+	return inputs, nil
+}
@@ -0,0 +1,86 @@
+package batch_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	interpreter "github.com/momaee/WL"
+	"github.com/momaee/WL/batch"
+)
+
+func compile(t *testing.T, code string) *interpreter.Program {
+	t.Helper()
+	p, err := interpreter.Compile(strings.NewReader(code))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestRunner_Run(t *testing.T) {
+	// cat up to the zero byte
+	r := &batch.Runner{Program: compile(t, ",[.,]"), Workers: 2, MaxSteps: 1000}
+	inputs := []batch.Input{
+		{Name: "a", Data: []byte("hello\x00")},
+		{Name: "b", Data: []byte("\x00")},
+		{Name: "long", Data: []byte(strings.Repeat("a", 1000) + "\x00")},
+	}
+
+	results := r.Run(context.Background(), inputs)
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results got %d", len(results))
+	}
+	if res := results[0]; res.Name != "a" || res.Status != batch.StatusOK || string(res.Output) != "hello" || res.Steps != 17 {
+		t.Errorf("unexpected result %+v", res)
+	}
+	if res := results[1]; res.Status != batch.StatusOK || len(res.Output) != 0 {
+		t.Errorf("unexpected result %+v", res)
+	}
+	if res := results[2]; res.Status != batch.StatusStepLimit || res.Error != "step limit exceeded" {
+		t.Errorf("unexpected result %+v", res)
+	}
+}
+
+func TestRunner_Timeout(t *testing.T) {
+	r := &batch.Runner{Program: compile(t, "+[]"), Timeout: 10 * time.Millisecond}
+	results := r.Run(context.Background(), []batch.Input{{Name: "loop"}})
+	if results[0].Status != batch.StatusTimeout {
+		t.Errorf("expected timeout got %+v", results[0])
+	}
+}
+
+func TestRunner_WriteJSON(t *testing.T) {
+	inputs, err := batch.FS(fstest.MapFS{
+		"in/1.txt": {Data: []byte("x\x00")},
+		"in/2.txt": {Data: []byte("y\x00")},
+		"in/3.txt": {Data: []byte("\xff\xfe\x00")},
+	}, "in")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	r := &batch.Runner{Program: compile(t, ",[.,]")}
+	if err := r.WriteJSON(context.Background(), &out, inputs); err != nil {
+		t.Fatal(err)
+	}
+
+	outputs := map[string]string{}
+	s := bufio.NewScanner(&out)
+	for s.Scan() {
+		var res batch.Result
+		if err := json.Unmarshal(s.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		outputs[res.Name] = string(res.Output)
+	}
+	if len(outputs) != 3 || outputs["in/1.txt"] != "x" || outputs["in/2.txt"] != "y" || outputs["in/3.txt"] != "\xff\xfe" {
+		t.Errorf("unexpected results %v", outputs)
+	}
+}
@@ -0,0 +1,133 @@
+package interpreter
+
+import (
+	"io"
+
+	"github.com/momaee/WL/parser"
+	"github.com/momaee/WL/token"
+)
+
+// Mode selects the language run by an interpreter.
+type Mode int
+
+const (
+	// Brainfuck runs the eight commands on a tape of bytes, the default.
+	Brainfuck Mode = iota
+	// Boolfuck works on a tape of bits: '+' flips the current bit, ',' reads and ';' writes a bit.
+	// bits are read from and written to the bytes of the I/O least significant bit first.
+	Boolfuck
+	// Smallfuck works on a finite tape of token.MemorySize bits: '*' flips the current bit, there is no I/O.
+	// the program ends when the pointer leaves the tape.
+	Smallfuck
+	// Paintfuck works on a toroidal grid of bits, see WithGrid:
+	// 'n', 's', 'e' and 'w' move the pointer up, down, right and left, '*' flips the current bit.
+	Paintfuck
+)
+
+// WithMode selects the language run by the interpreter.
+func WithMode(m Mode) Option {
+	return func(b *brainFuck) {
+		b.mode = m
+	}
+}
+
+// bitIO buffers the bits of one byte, n is the number of bits left to read or already written.
+type bitIO struct {
+	bits byte
+	n    int
+}
+
+// symbols returns the commands of the language of m, nil for Brainfuck.
+func (m Mode) symbols() map[rune]*token.Token {
+	switch m {
+	case Boolfuck:
+		return token.BoolfuckTokens
+	case Smallfuck:
+		return token.SmallfuckTokens
+	case Paintfuck:
+		return token.PaintfuckTokens
+	}
+	return nil
+}
+
+// stepBits executes one instruction on the bit tape.
+func (b *brainFuck) stepBits(in *parser.Inst) error {
+	c := in.C
+	switch in.T.Tok {
+	case token.LeftToken:
+		b.bits.Cursor -= c
+
+	case token.RightToken:
+		b.bits.Cursor += c
+
+	case token.FlipToken:
+		if c%2 == 1 {
+			b.bits.Flip(b.bits.Cursor)
+		}
+
+	case token.PrintToken:
+		for i := 0; i < c && b.err == nil; i++ {
+			b.writeBit(b.bits.Get(b.bits.Cursor))
+		}
+
+	case token.ReadToken:
+		for i := 0; i < c && b.err == nil; i++ {
+			b.bits.Set(b.bits.Cursor, b.readBit())
+		}
+
+	case token.LeftBracketToken:
+		if !b.bits.Get(b.bits.Cursor) {
+			b.ip = c
+		}
+
+	case token.RightBracketToken:
+		if b.bits.Get(b.bits.Cursor) {
+			b.ip = c
+		}
+	}
+
+	if b.mode == Smallfuck && (b.bits.Cursor < 0 || b.bits.Cursor >= token.MemorySize) {
+		b.ip = len(b.inst)
+	}
+	return nil
+}
+
+// readBit returns the next bit of the input, zero at the end of the input.
+func (b *brainFuck) readBit() bool {
+	if b.bitIn.n == 0 {
+		if _, err := b.i.Read(b.buf); err != nil {
+			if err != io.EOF {
+				b.err = err
+			}
+			return false
+		}
+		b.bitIn = bitIO{bits: b.buf[0], n: 8}
+	}
+	bit := b.bitIn.bits&1 != 0
+	b.bitIn.bits >>= 1
+	b.bitIn.n--
+	return bit
+}
+
+// writeBit adds a bit to the output, a byte is written once it has eight bits.
+func (b *brainFuck) writeBit(bit bool) {
+	if bit {
+		b.bitOut.bits |= 1 << uint(b.bitOut.n)
+	}
+	b.bitOut.n++
+	if b.bitOut.n == 8 {
+		b.flushBits()
+	}
+}
+
+// flushBits writes the buffered output bits, the missing high bits are zero.
+func (b *brainFuck) flushBits() {
+	if b.bitOut.n == 0 {
+		return
+	}
+	b.buf[0] = b.bitOut.bits
+	b.bitOut = bitIO{}
+	if _, err := b.w.Write(b.buf); err != nil && b.err == nil {
+		b.err = err
+	}
+}
@@ -0,0 +1,47 @@
+// Package boolfuck converts Brainfuck programs to Boolfuck.
+//
+// Every cell of Brainfuck takes nine bits of the Boolfuck tape,
+// a flag bit used by the conversion followed by the eight bits of the value, least significant first.
+package boolfuck
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/momaee/WL/lexer"
+	"github.com/momaee/WL/token"
+)
+
+// commands are the Boolfuck code of each Brainfuck command.
+var commands = map[token.Type]string{
+	token.PlusToken:         ">[>]+<[+<]>>>>>>>>>[+]<<<<<<<<<",
+	token.MinusToken:        ">>>>>>>>>+<<<<<<<<+[>+]<[<]>>>>>>>>>[+]<<<<<<<<<",
+	token.LeftToken:         "<<<<<<<<<",
+	token.RightToken:        ">>>>>>>>>",
+	token.ReadToken:         ">,>,>,>,>,>,>,>,<<<<<<<<",
+	token.PrintToken:        ">;>;>;>;>;>;>;>;<<<<<<<<",
+	token.LeftBracketToken:  ">>>>>>>>>+<<<<<<<<+[>+]<[<]>>>>>>>>>[+<<<<<<<<[>]+<[+<]",
+	token.RightBracketToken: ">>>>>>>>>+<<<<<<<<+[>+]<[<]>>>>>>>>>]<[+<]",
+}
+
+// FromBrainfuck reads a Brainfuck program from r and writes the equivalent Boolfuck program to w.
+// comments are dropped, the operators added to the registry are ignored.
+func FromBrainfuck(w io.Writer, r io.Reader) error {
+	symbols := map[string]*token.Token{}
+	for t := range commands {
+		tok := token.Builtin(t)
+		symbols[tok.Value] = tok
+	}
+
+	out := bufio.NewWriter(w)
+	l := lexer.NewScanner(r, lexer.WithSymbols(symbols))
+	for {
+		tok := l.Scan()
+		if tok.Tok == token.EOFToken {
+			return out.Flush()
+		}
+		if _, err := out.WriteString(commands[tok.Tok]); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package boolfuck_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	interpreter "github.com/momaee/WL"
+	"github.com/momaee/WL/boolfuck"
+)
+
+func TestFromBrainfuck(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "hello world",
+			code:     "++++++++[>++++[>++>+++>+++>+<<<<-]>+>+>->>+[<]<-]>>.>---.+++++++..+++.>>.<-.<.+++.------.--------.>>+.>++.",
+			expected: "Hello World!\n",
+		},
+		{
+			name:     "cat",
+			code:     ",[.,]",
+			input:    "echo",
+			expected: "echo",
+		},
+		{
+			name:     "wrap",
+			code:     "-.+.",
+			expected: "\xff\x00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var code bytes.Buffer
+			if err := boolfuck.FromBrainfuck(&code, strings.NewReader(tt.code)); err != nil {
+				t.Fatal(err)
+			}
+
+			output := new(bytes.Buffer)
+			bfm := interpreter.NewInterpreter(strings.NewReader(tt.input), output, &code, interpreter.WithMode(interpreter.Boolfuck))
+			if err := bfm.Run(); err != nil {
+				t.Fatal(err)
+			}
+			if output.String() != tt.expected {
+				t.Errorf("expected %q got %q", tt.expected, output.String())
+			}
+		})
+	}
+}
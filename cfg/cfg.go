@@ -0,0 +1,276 @@
+// Package cfg turns the bracket links of parsed instructions (Inst.C)
+// into a loop tree and a basic block control-flow graph.
+// Both can be exported as Graphviz DOT and JSON.
+package cfg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/momaee/WL/parser"
+	"github.com/momaee/WL/token"
+)
+
+// Loop is a node of the loop tree.
+// Open and Close are the indexes of the brackets, both are -1 for the root which covers the whole program.
+// Net is the pointer movement of one pass through the body,
+// Exact is false if it depends on a nested loop which does not return to its starting cell.
+type Loop struct {
+	Open     int       `json:"open"`
+	Close    int       `json:"close"`
+	Pos      token.Pos `json:"pos"`
+	End      token.Pos `json:"end"`
+	Depth    int       `json:"depth"`
+	Net      int       `json:"net"`
+	Exact    bool      `json:"exact"`
+	Children []*Loop   `json:"children,omitempty"`
+}
+
+// Block is a basic block, the instructions in [Start, End).
+// a block ends with a bracket or right before the instruction following one.
+// Succs are the ids of the successor blocks, for a block ending with a bracket
+// the first one is taken if the current cell is not zero and the second one otherwise.
+type Block struct {
+	ID     int       `json:"id"`
+	Start  int       `json:"start"`
+	End    int       `json:"end"`
+	Pos    token.Pos `json:"pos"`
+	EndPos token.Pos `json:"endPos"`
+	Net    int       `json:"net"`
+	Succs  []int     `json:"succs"`
+
+	text string
+}
+
+// Graph is the control-flow graph of a program.
+// Blocks[0] is the entry block, the last block is the empty exit block.
+type Graph struct {
+	Blocks []*Block `json:"blocks"`
+	Loops  *Loop    `json:"loops"`
+}
+
+// Build creates the control-flow graph and the loop tree of inst.
+// err != nil if the brackets are not matched.
+func Build(inst []*parser.Inst) (*Graph, error) {
+	loops, err := LoopTree(inst)
+	if err != nil {
+		return nil, err
+	}
+
+	// every bracket ends a block
+	leaders := []int{0}
+	for i, in := range inst {
+		if isBracket(in) {
+			leaders = append(leaders, i+1)
+		}
+	}
+
+	g := &Graph{Loops: loops}
+	ids := map[int]int{}
+	for i, start := range leaders {
+		if (i > 0 && start == leaders[i-1]) || start == len(inst) {
+			continue
+		}
+		end := len(inst)
+		if i+1 < len(leaders) {
+			end = leaders[i+1]
+		}
+		ids[start] = len(g.Blocks)
+		g.Blocks = append(g.Blocks, newBlock(len(g.Blocks), inst, start, end))
+	}
+	exit := &Block{ID: len(g.Blocks), Start: len(inst), End: len(inst)}
+	if len(inst) > 0 {
+		exit.Pos = inst[len(inst)-1].End
+		exit.EndPos = exit.Pos
+	}
+	ids[len(inst)] = exit.ID
+	g.Blocks = append(g.Blocks, exit)
+
+	for _, b := range g.Blocks[:len(g.Blocks)-1] {
+		last := inst[b.End-1]
+		switch last.T.Tok {
+		case token.LeftBracketToken:
+			b.Succs = []int{ids[b.End], ids[last.C+1]}
+		case token.RightBracketToken:
+			b.Succs = []int{ids[last.C+1], ids[b.End]}
+		default:
+			b.Succs = []int{ids[b.End]}
+		}
+	}
+	return g, nil
+}
+
+func newBlock(id int, inst []*parser.Inst, start, end int) *Block {
+	b := &Block{
+		ID:     id,
+		Start:  start,
+		End:    end,
+		Pos:    inst[start].Pos,
+		EndPos: inst[end-1].End,
+	}
+	var text strings.Builder
+	for _, in := range inst[start:end] {
+		b.Net += move(in)
+		if isBracket(in) {
+			text.WriteString(in.T.Value)
+		} else {
+			text.WriteString(strings.Repeat(in.T.Value, in.C))
+		}
+	}
+	b.text = text.String()
+	return b
+}
+
+// LoopTree returns the root of the loop tree of inst.
+// err != nil if the brackets are not matched.
+func LoopTree(inst []*parser.Inst) (*Loop, error) {
+	root := &Loop{Open: -1, Close: -1, Exact: true}
+	if len(inst) > 0 {
+		root.Pos = inst[0].Pos
+		root.End = inst[len(inst)-1].End
+	}
+
+	stack := []*Loop{root}
+	for i, in := range inst {
+		parent := stack[len(stack)-1]
+		switch in.T.Tok {
+		case token.LeftBracketToken:
+			if in.C <= i || in.C >= len(inst) || inst[in.C].T.Tok != token.RightBracketToken {
+				return nil, fmt.Errorf("%v: unmatched [", in.Pos)
+			}
+			l := &Loop{Open: i, Close: in.C, Pos: in.Pos, End: inst[in.C].End, Depth: len(stack), Exact: true}
+			parent.Children = append(parent.Children, l)
+			stack = append(stack, l)
+
+		case token.RightBracketToken:
+			if len(stack) == 1 || parent.Open != in.C {
+				return nil, fmt.Errorf("%v: unmatched ]", in.Pos)
+			}
+			stack = stack[:len(stack)-1]
+			grand := stack[len(stack)-1]
+			if parent.Net != 0 || !parent.Exact {
+				grand.Exact = false
+			}
+
+		default:
+			parent.Net += move(in)
+		}
+	}
+	if len(stack) != 1 {
+		return nil, fmt.Errorf("%v: unmatched [", stack[len(stack)-1].Pos)
+	}
+	return root, nil
+}
+
+// WriteJSON writes the graph including the loop tree as indented JSON.
+func (g *Graph) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(g)
+}
+
+// WriteDOT writes the graph in Graphviz DOT format.
+// blocks are grouped in nested clusters following the loop tree.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph cfg {\n")
+	b.WriteString("\tnode [shape=box fontname=\"monospace\"];\n")
+
+	inLoop := map[*Loop][]*Block{}
+	for _, blk := range g.Blocks {
+		l := g.innermost(blk)
+		inLoop[l] = append(inLoop[l], blk)
+	}
+	g.writeCluster(&b, g.Loops, inLoop, "\t")
+
+	for _, blk := range g.Blocks {
+		for i, s := range blk.Succs {
+			label := ""
+			if len(blk.Succs) == 2 {
+				label = [...]string{" [label=\"nonzero\"]", " [label=\"zero\"]"}[i]
+			}
+			fmt.Fprintf(&b, "\tb%d -> b%d%s;\n", blk.ID, s, label)
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (g *Graph) writeCluster(b *strings.Builder, l *Loop, inLoop map[*Loop][]*Block, indent string) {
+	if l.Open >= 0 {
+		fmt.Fprintf(b, "%ssubgraph cluster_%d {\n", indent, l.Open)
+		fmt.Fprintf(b, "%s\tlabel=%s;\n", indent, quote(fmt.Sprintf("loop %v-%v net %s", l.Pos, l.End, net(l.Net, l.Exact))))
+		indent += "\t"
+	}
+	for _, blk := range inLoop[l] {
+		label := "exit"
+		if blk.Start < blk.End {
+			label = fmt.Sprintf("b%d %v-%v\n%s\nnet %+d", blk.ID, blk.Pos, blk.EndPos, shorten(blk.text, 32), blk.Net)
+		}
+		fmt.Fprintf(b, "%sb%d [label=%s];\n", indent, blk.ID, quote(label))
+	}
+	for _, c := range l.Children {
+		g.writeCluster(b, c, inLoop, indent)
+	}
+	if l.Open >= 0 {
+		fmt.Fprintf(b, "%s}\n", indent[:len(indent)-1])
+	}
+}
+
+// innermost returns the deepest loop containing the first instruction of blk.
+// blocks starting right after a closing bracket belong to the enclosing loop.
+func (g *Graph) innermost(blk *Block) *Loop {
+	l := g.Loops
+	for {
+		var next *Loop
+		for _, c := range l.Children {
+			if c.Open < blk.Start && blk.Start <= c.Close {
+				next = c
+				break
+			}
+		}
+		if next == nil {
+			return l
+		}
+		l = next
+	}
+}
+
+// move returns the pointer movement of in.
+func move(in *parser.Inst) int {
+	switch in.T.Tok {
+	case token.RightToken:
+		return in.C
+	case token.LeftToken:
+		return -in.C
+	}
+	return 0
+}
+
+func isBracket(in *parser.Inst) bool {
+	return in.T.Tok == token.LeftBracketToken || in.T.Tok == token.RightBracketToken
+}
+
+func net(n int, exact bool) string {
+	if !exact {
+		return "unknown"
+	}
+	return fmt.Sprintf("%+d", n)
+}
+
+func shorten(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max-3] + "..."
+}
+
+// quote returns s as a DOT string, new lines are written as centered line breaks.
+func quote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
@@ -0,0 +1,119 @@
+package cfg_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/momaee/WL/cfg"
+	"github.com/momaee/WL/lexer"
+	"github.com/momaee/WL/parser"
+	"github.com/momaee/WL/token"
+)
+
+func build(t *testing.T, src string) *cfg.Graph {
+	t.Helper()
+	g, err := cfg.Build(parser.NewParser(lexer.NewScanner(strings.NewReader(src))).Parse())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestBuild_Blocks(t *testing.T) {
+	// instructions: 0:+ 1:[ 2:- 3:> 4:+ 5:< 6:] 7:> 8:.
+	g := build(t, "++[->+<]>.")
+
+	expected := []struct {
+		start, end int
+		net        int
+		succs      []int
+	}{
+		{0, 2, 0, []int{1, 2}},
+		{2, 7, 0, []int{1, 2}},
+		{7, 9, 1, []int{3}},
+		{9, 9, 0, nil},
+	}
+	if len(g.Blocks) != len(expected) {
+		t.Fatalf("expected %d blocks got %d", len(expected), len(g.Blocks))
+	}
+	for i, e := range expected {
+		b := g.Blocks[i]
+		if b.Start != e.start || b.End != e.end || b.Net != e.net || !reflect.DeepEqual(b.Succs, e.succs) {
+			t.Errorf("block %d: expected %+v got %+v", i, e, *b)
+		}
+	}
+}
+
+func TestLoopTree(t *testing.T) {
+	g := build(t, "+[>[-]>[<]<]")
+
+	root := g.Loops
+	if len(root.Children) != 1 {
+		t.Fatalf("expected one top level loop, got %d", len(root.Children))
+	}
+	outer := root.Children[0]
+	if outer.Open != 1 || outer.Close != 11 || outer.Depth != 1 {
+		t.Errorf("wrong outer loop %+v", *outer)
+	}
+	if outer.Pos.Column != 2 || outer.End.Column != 13 {
+		t.Errorf("wrong source range %v-%v", outer.Pos, outer.End)
+	}
+	if outer.Exact || len(outer.Children) != 2 {
+		t.Errorf("expected inexact loop with two children, got %+v", *outer)
+	}
+	if inner := outer.Children[1]; inner.Net != -1 || !inner.Exact || inner.Depth != 2 {
+		t.Errorf("wrong inner loop %+v", *inner)
+	}
+}
+
+func TestBuild_Unmatched(t *testing.T) {
+	// instructions which are not linked by the parser
+	inst := []*parser.Inst{
+		{T: token.Builtin(token.PlusToken), C: 1},
+		{T: token.Builtin(token.LeftBracketToken), C: 0},
+	}
+	if _, err := cfg.Build(inst); err == nil {
+		t.Errorf("expected error for unmatched bracket")
+	}
+
+	// the parser leaves the unmatched bracket out
+	if _, err := cfg.Build(parser.NewParser(lexer.NewScanner(strings.NewReader("+[-"))).Parse()); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	var out bytes.Buffer
+	if err := build(t, "+[-]").WriteDOT(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"subgraph cluster_1 {",
+		`b1 [label="b1 1:3-1:5\n-]\nnet +0"];`,
+		`b0 -> b1 [label="nonzero"];`,
+		`b1 -> b2 [label="zero"];`,
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("expected %q in\n%s", line, out.String())
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var out bytes.Buffer
+	g := build(t, "+[-]")
+	if err := g.WriteJSON(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded cfg.Graph
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Blocks) != len(g.Blocks) || decoded.Loops.Children[0].Pos != g.Loops.Children[0].Pos {
+		t.Errorf("JSON does not round trip:\n%s", out.String())
+	}
+}
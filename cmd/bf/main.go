@@ -0,0 +1,194 @@
+// Command bf runs and formats Brainfuck programs.
+//
+// Usage:
+//
+//	bf run file
+//	bf fmt [-w] [-m] [-indent string] [-width n] [files]
+//	bf batch [-workers n] [-steps n] [-timeout d] file inputs...
+//	bf conformance [-cells n] [-eof mode] [-wrap]
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	interpreter "github.com/momaee/WL"
+	"github.com/momaee/WL/batch"
+	"github.com/momaee/WL/conformance"
+	"github.com/momaee/WL/format"
+)
+
+const usage = `usage: bf <command> [arguments]
+
+commands:
+  run    run a program, reading input from stdin and writing output to stdout
+  fmt    format or minify programs
+  batch  run a program once for every input file, writing JSON lines to stdout
+  conformance
+         report the behavior of the interpreter in the portability tests
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "run":
+		err = run(args)
+	case "fmt":
+		err = fmtCmd(args)
+	case "batch":
+		err = batchCmd(args)
+	case "conformance":
+		err = conformanceCmd(args)
+	default:
+		fmt.Fprintf(os.Stderr, "bf: unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "bf:", err)
+		os.Exit(1)
+	}
+}
+
+// run executes the program in the file given as the only argument.
+func run(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("run expects exactly one file")
+	}
+
+	code, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer code.Close()
+
+	// the interpreter buffers the output itself and flushes it before it waits for input
+	return interpreter.NewInterpreter(os.Stdin, os.Stdout, code).Run()
+}
+
+// fmtCmd formats the given files, or stdin if there are none.
+func fmtCmd(args []string) error {
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := fs.Bool("w", false, "write result to the source file instead of stdout")
+	minify := fs.Bool("m", false, "minify instead of pretty-printing")
+	indent := fs.String("indent", format.DefaultOptions.Indent, "indentation per loop nesting level")
+	width := fs.Int("width", format.DefaultOptions.Width, "maximum line width, 0 disables wrapping")
+	_ = fs.Parse(args)
+
+	opts := format.Options{Indent: *indent, Width: *width}
+	process := func(src []byte) ([]byte, error) {
+		if *minify {
+			return format.Minify(bytes.NewReader(src))
+		}
+		return format.Source(bytes.NewReader(src), opts)
+	}
+
+	if fs.NArg() == 0 {
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		out, err := process(src)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+
+	for _, name := range fs.Args() {
+		src, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		out, err := process(src)
+		if err != nil {
+			return fmt.Errorf("%s:%v", name, err)
+		}
+		if *write {
+			err = os.WriteFile(name, out, 0644)
+		} else {
+			_, err = os.Stdout.Write(out)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// batchCmd runs the program in the first file with every input file or directory given after it.
+func batchCmd(args []string) error {
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	workers := fs.Int("workers", 0, "number of programs run at the same time, 0 means one per CPU")
+	steps := fs.Int("steps", 0, "maximum instructions per input, 0 means no limit")
+	timeout := fs.Duration("timeout", 0, "maximum time per input, 0 means no limit")
+	_ = fs.Parse(args)
+	if fs.NArg() < 2 {
+		return fmt.Errorf("batch expects a file and at least one input")
+	}
+
+	code, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer code.Close()
+
+	program, err := interpreter.Compile(code)
+	if err != nil {
+		return fmt.Errorf("%s:%v", fs.Arg(0), err)
+	}
+	inputs, err := batch.Files(fs.Args()[1:]...)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	r := &batch.Runner{Program: program, Workers: *workers, MaxSteps: *steps, Timeout: *timeout}
+	return r.WriteJSON(context.Background(), w, inputs)
+}
+
+// conformanceCmd prints the compatibility report of the interpreter configured by the flags.
+func conformanceCmd(args []string) error {
+	fs := flag.NewFlagSet("conformance", flag.ExitOnError)
+	cells := fs.Int("cells", interpreter.DefaultCellSize, "number of bits of a cell: 8, 16 or 32")
+	eof := fs.String("eof", "error", "behavior at the end of the input: error, unchanged, zero or minus-one")
+	wrap := fs.Bool("wrap", false, "wrap the pointer around the ends of the tape")
+	_ = fs.Parse(args)
+
+	modes := map[string]interpreter.EOFMode{
+		"error":     interpreter.EOFError,
+		"unchanged": interpreter.EOFUnchanged,
+		"zero":      interpreter.EOFZero,
+		"minus-one": interpreter.EOFMinusOne,
+	}
+	mode, ok := modes[*eof]
+	if !ok {
+		return fmt.Errorf("unknown EOF mode %q", *eof)
+	}
+	opts := []interpreter.Option{interpreter.WithCellSize(*cells), interpreter.WithEOF(mode)}
+	if *wrap {
+		opts = append(opts, interpreter.WithWrappingTape())
+	}
+
+	report := conformance.Run(opts...)
+	fmt.Print(report)
+	if !report.Passed() {
+		return fmt.Errorf("conformance tests failed")
+	}
+	return nil
+}
@@ -0,0 +1,451 @@
+// Package compiler translates a tiny structured language into Brainfuck.
+//
+// A program is a list of statements:
+//
+//	var x = 'A';        // declare a byte variable, the value is optional
+//	x = x + 2 - y;      // assignment, arithmetic is modulo 256
+//	if x { ... } else { ... }
+//	while x { ... }     // conditions are true if the value is not zero
+//	print x + 1;        // print the byte value of an expression
+//	print "text\n";     // print a string
+//	read x;             // read one byte of input into x
+//
+// Expressions are built from variables, numbers (0-255), character literals,
+// parentheses, unary minus and the + and - operators.
+// Variables are global and have to be declared before they are used.
+package compiler
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/scanner"
+
+	"github.com/momaee/WL/token"
+)
+
+// Error is a compile error at a position in the source.
+type Error struct {
+	Pos token.Pos
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v: %s", e.Pos, e.Msg)
+}
+
+// Compile reads a program from src and returns the equivalent Brainfuck code.
+// variables are stored from cell 0 upwards, temporary cells follow them.
+// err is an *Error if the program is not valid.
+func Compile(src io.Reader) (code string, err error) {
+	p := &parser{vars: map[string]int{}}
+	p.s.Init(src)
+	p.s.Mode = scanner.ScanIdents | scanner.ScanInts | scanner.ScanChars | scanner.ScanStrings |
+		scanner.ScanComments | scanner.SkipComments
+	p.s.Error = func(s *scanner.Scanner, msg string) {
+		p.fail(s.Pos(), msg)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*Error)
+			if !ok {
+				panic(r)
+			}
+			err = e
+		}
+	}()
+
+	p.next()
+	var prog []stmt
+	for p.tok != scanner.EOF {
+		prog = append(prog, p.stmt())
+	}
+
+	g := &generator{free: len(p.vars), max: len(p.vars) - 1}
+	g.stmts(prog)
+	if g.max >= token.MemorySize {
+		return "", &Error{Msg: fmt.Sprintf("program needs %d cells, only %d are available", g.max+1, token.MemorySize)}
+	}
+	return g.out.String(), nil
+}
+
+// statements of the syntax tree
+type (
+	stmt interface{}
+
+	assignStmt struct {
+		cell  int
+		value expr
+	}
+
+	printStmt struct {
+		value expr
+		text  []byte
+	}
+
+	readStmt struct {
+		cell int
+	}
+
+	ifStmt struct {
+		cond expr
+		then []stmt
+		els  []stmt
+	}
+
+	whileStmt struct {
+		cond expr
+		body []stmt
+	}
+)
+
+// expr is a sum of terms.
+type expr []term
+
+// term is a constant, a variable or a nested expression, added or subtracted.
+type term struct {
+	neg   bool
+	value int
+	cell  int // -1 if the term is not a variable
+	sub   expr
+}
+
+// parser builds the syntax tree and assigns a cell to each variable.
+type parser struct {
+	s    scanner.Scanner
+	tok  rune
+	lit  string
+	pos  scanner.Position
+	vars map[string]int
+}
+
+func (p *parser) next() {
+	p.tok = p.s.Scan()
+	p.lit = p.s.TokenText()
+	p.pos = p.s.Position
+}
+
+// fail stops the compilation with an error at pos.
+func (p *parser) fail(pos scanner.Position, format string, args ...interface{}) {
+	panic(&Error{
+		Pos: token.Pos{Offset: pos.Offset, Line: pos.Line, Column: pos.Column},
+		Msg: fmt.Sprintf(format, args...),
+	})
+}
+
+func (p *parser) expect(lit string) {
+	if p.lit != lit {
+		p.fail(p.pos, "expected %q, found %q", lit, p.lit)
+	}
+	p.next()
+}
+
+// ident returns the name at the current position.
+func (p *parser) ident() string {
+	if p.tok != scanner.Ident || keywords[p.lit] {
+		p.fail(p.pos, "expected variable name, found %q", p.lit)
+	}
+	name := p.lit
+	p.next()
+	return name
+}
+
+// variable returns the cell of an already declared variable.
+func (p *parser) variable() int {
+	pos := p.pos
+	name := p.ident()
+	cell, ok := p.vars[name]
+	if !ok {
+		p.fail(pos, "undefined variable %s", name)
+	}
+	return cell
+}
+
+var keywords = map[string]bool{"var": true, "if": true, "else": true, "while": true, "print": true, "read": true}
+
+func (p *parser) stmt() stmt {
+	switch p.lit {
+	case "var":
+		p.next()
+		pos := p.pos
+		name := p.ident()
+		if _, ok := p.vars[name]; ok {
+			p.fail(pos, "variable %s already declared", name)
+		}
+		cell := len(p.vars)
+		p.vars[name] = cell
+		s := &assignStmt{cell: cell}
+		if p.lit == "=" {
+			p.next()
+			s.value = p.expr()
+		}
+		p.expect(";")
+		return s
+
+	case "print":
+		p.next()
+		s := &printStmt{}
+		if p.tok == scanner.String {
+			text, err := strconv.Unquote(p.lit)
+			if err != nil {
+				p.fail(p.pos, "invalid string %s", p.lit)
+			}
+			s.text = []byte(text)
+			p.next()
+		} else {
+			s.value = p.expr()
+		}
+		p.expect(";")
+		return s
+
+	case "read":
+		p.next()
+		s := &readStmt{cell: p.variable()}
+		p.expect(";")
+		return s
+
+	case "if":
+		p.next()
+		s := &ifStmt{cond: p.expr(), then: p.block()}
+		if p.lit == "else" {
+			p.next()
+			if p.lit == "if" {
+				s.els = []stmt{p.stmt()}
+			} else {
+				s.els = p.block()
+			}
+		}
+		return s
+
+	case "while":
+		p.next()
+		return &whileStmt{cond: p.expr(), body: p.block()}
+	}
+
+	if p.tok != scanner.Ident {
+		p.fail(p.pos, "expected statement, found %q", p.lit)
+	}
+	s := &assignStmt{cell: p.variable()}
+	p.expect("=")
+	s.value = p.expr()
+	p.expect(";")
+	return s
+}
+
+func (p *parser) block() []stmt {
+	p.expect("{")
+	var stmts []stmt
+	for p.lit != "}" {
+		if p.tok == scanner.EOF {
+			p.fail(p.pos, "expected \"}\", found end of file")
+		}
+		stmts = append(stmts, p.stmt())
+	}
+	p.next()
+	return stmts
+}
+
+func (p *parser) expr() expr {
+	e := expr{p.term(false)}
+	for p.lit == "+" || p.lit == "-" {
+		neg := p.lit == "-"
+		p.next()
+		e = append(e, p.term(neg))
+	}
+	return e
+}
+
+func (p *parser) term(neg bool) term {
+	if p.lit == "-" {
+		p.next()
+		return p.term(!neg)
+	}
+
+	t := term{neg: neg, cell: -1}
+	switch p.tok {
+	case scanner.Ident:
+		t.cell = p.variable()
+		return t
+
+	case scanner.Int:
+		v, err := strconv.Atoi(p.lit)
+		if err != nil || v > 255 {
+			p.fail(p.pos, "number %s out of range 0-255", p.lit)
+		}
+		t.value = v
+
+	case scanner.Char:
+		s, err := strconv.Unquote(p.lit)
+		if err != nil || len(s) != 1 {
+			p.fail(p.pos, "character %s is not a single byte", p.lit)
+		}
+		t.value = int(s[0])
+
+	default:
+		if p.lit != "(" {
+			p.fail(p.pos, "expected expression, found %q", p.lit)
+		}
+		p.next()
+		t.sub = p.expr()
+		p.expect(")")
+		return t
+	}
+	p.next()
+	return t
+}
+
+// generator emits the code of the syntax tree.
+// ptr is the cell the pointer is on, which is always known while generating.
+// temporary cells are allocated from free upwards and are zero when released.
+type generator struct {
+	out  strings.Builder
+	ptr  int
+	free int
+	max  int
+}
+
+func (g *generator) alloc() int {
+	c := g.free
+	g.free++
+	if c > g.max {
+		g.max = c
+	}
+	return c
+}
+
+func (g *generator) release(c int) {
+	g.free = c
+}
+
+func (g *generator) stmts(stmts []stmt) {
+	for _, s := range stmts {
+		g.stmt(s)
+	}
+}
+
+func (g *generator) stmt(s stmt) {
+	switch s := s.(type) {
+	case *assignStmt:
+		t := g.alloc()
+		g.eval(t, s.value, false)
+		g.clear(s.cell)
+		g.moveValue(t, s.cell)
+		g.release(t)
+
+	case *printStmt:
+		t := g.alloc()
+		if s.value != nil {
+			g.eval(t, s.value, false)
+			g.emit(t, ".")
+		}
+		cur := 0
+		for _, c := range s.text {
+			g.addConst(t, int(c)-cur)
+			g.emit(t, ".")
+			cur = int(c)
+		}
+		g.clear(t)
+		g.release(t)
+
+	case *readStmt:
+		g.emit(s.cell, ",")
+
+	case *ifStmt:
+		t := g.alloc()
+		g.eval(t, s.cond, false)
+		e := -1
+		if s.els != nil {
+			e = g.alloc()
+			g.addConst(e, 1)
+		}
+		g.emit(t, "[")
+		g.clear(t)
+		if e >= 0 {
+			g.emit(e, "-")
+		}
+		g.stmts(s.then)
+		g.emit(t, "]")
+		if e >= 0 {
+			g.emit(e, "[-")
+			g.stmts(s.els)
+			g.emit(e, "]")
+		}
+		g.release(t)
+
+	case *whileStmt:
+		t := g.alloc()
+		g.eval(t, s.cond, false)
+		g.emit(t, "[")
+		g.clear(t)
+		g.stmts(s.body)
+		g.eval(t, s.cond, false)
+		g.emit(t, "]")
+		g.release(t)
+	}
+}
+
+// eval adds the value of e to cell dst, or subtracts it if neg is set.
+func (g *generator) eval(dst int, e expr, neg bool) {
+	for _, t := range e {
+		n := neg != t.neg
+		switch {
+		case t.sub != nil:
+			g.eval(dst, t.sub, n)
+		case t.cell >= 0:
+			g.addVar(dst, t.cell, n)
+		case n:
+			g.addConst(dst, -t.value)
+		default:
+			g.addConst(dst, t.value)
+		}
+	}
+}
+
+// addVar adds the value of src to dst, or subtracts it if neg is set, keeping src.
+func (g *generator) addVar(dst, src int, neg bool) {
+	op := "+"
+	if neg {
+		op = "-"
+	}
+	t := g.alloc()
+	g.emit(src, "[")
+	g.emit(dst, op)
+	g.emit(t, "+")
+	g.emit(src, "-]")
+	g.moveValue(t, src)
+	g.release(t)
+}
+
+// moveValue adds the value of src to dst and sets src to zero.
+func (g *generator) moveValue(src, dst int) {
+	g.emit(src, "[")
+	g.emit(dst, "+")
+	g.emit(src, "-]")
+}
+
+// addConst adds n modulo 256 to cell c, using the shorter direction.
+func (g *generator) addConst(c, n int) {
+	n = ((n % 256) + 256) % 256
+	if n <= 128 {
+		g.emit(c, strings.Repeat("+", n))
+	} else {
+		g.emit(c, strings.Repeat("-", 256-n))
+	}
+}
+
+func (g *generator) clear(c int) {
+	g.emit(c, "[-]")
+}
+
+// emit moves the pointer to cell c and writes code.
+func (g *generator) emit(c int, code string) {
+	if c > g.ptr {
+		g.out.WriteString(strings.Repeat(">", c-g.ptr))
+	} else {
+		g.out.WriteString(strings.Repeat("<", g.ptr-c))
+	}
+	g.ptr = c
+	g.out.WriteString(code)
+}
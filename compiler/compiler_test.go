@@ -0,0 +1,113 @@
+package compiler_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	interpreter "github.com/momaee/WL"
+	"github.com/momaee/WL/compiler"
+)
+
+func run(t *testing.T, src, input string) string {
+	t.Helper()
+	code, err := compiler.Compile(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := new(bytes.Buffer)
+	bfm := interpreter.NewInterpreter(strings.NewReader(input), output, strings.NewReader(code))
+	if err := bfm.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return output.String()
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		input    string
+		expected string
+	}{
+		{
+			name:     "print string",
+			src:      `print "Hello World!\n";`,
+			expected: "Hello World!\n",
+		},
+		{
+			name: "arithmetic",
+			src: `var a = 'A';
+				var b = 3;
+				print a + b - 1;
+				b = a - (b - 5);  // 65 - (-2)
+				print b;
+				print -(0 - a);`,
+			expected: "CCA",
+		},
+		{
+			name: "while",
+			src: `var i = 5;
+				var c = 'a';
+				while i {
+					print c;
+					c = c + 1;
+					i = i - 1;
+				}`,
+			expected: "abcde",
+		},
+		{
+			name: "if else",
+			src: `var x;
+				read x;
+				while x - '.' {
+					if x - 'b' {
+						print x;
+					} else if 0 {
+						print "never";
+					} else {
+						print "B";
+					}
+					read x;
+				}`,
+			input:    "abcb.",
+			expected: "aBcB",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if out := run(t, tt.src, tt.input); out != tt.expected {
+				t.Errorf("expected %q got %q", tt.expected, out)
+			}
+		})
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		src string
+		err string
+	}{
+		{"print x;", "1:7: undefined variable x"},
+		{"var x;\nvar x;", "2:5: variable x already declared"},
+		{"var x = 256;", "1:9: number 256 out of range 0-255"},
+		{"var x = 1", `1:10: expected ";", found ""`},
+		{"while 1 {\n print 1;", `2:10: expected "}", found end of file`},
+		{"var while;", `1:5: expected variable name, found "while"`},
+		{"x + 1;", `1:1: undefined variable x`},
+	}
+
+	for _, tt := range tests {
+		_, err := compiler.Compile(strings.NewReader(tt.src))
+		var cerr *compiler.Error
+		if !errors.As(err, &cerr) {
+			t.Errorf("%q: expected compile error, got %v", tt.src, err)
+			continue
+		}
+		if err.Error() != tt.err {
+			t.Errorf("%q: expected %q got %q", tt.src, tt.err, err.Error())
+		}
+	}
+}
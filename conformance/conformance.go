@@ -0,0 +1,189 @@
+// Package conformance runs the well-known portability tests of Brainfuck against an interpreter configuration.
+//
+// Brainfuck programs assume different behaviors for the end of the input, the size of the cells and the ends of the tape.
+// Each test reveals one of them, Run collects them in a compatibility report:
+//
+//	report := conformance.Run(interpreter.WithEOF(interpreter.EOFZero))
+//	fmt.Print(report)
+package conformance
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	interpreter "github.com/momaee/WL"
+)
+
+// MaxSteps is the step limit of every test, unless set by interpreter.WithMaxSteps.
+const MaxSteps = 10000000
+
+//go:embed tests/*.b
+var sources embed.FS
+
+// Outcome is a behavior revealed by a test, either by its output or by its error, which is matched with errors.Is.
+type Outcome struct {
+	Output   string
+	Err      error
+	Behavior string
+}
+
+// Test is a portability test, the first outcome is the behavior most programs assume.
+type Test struct {
+	Name     string
+	Code     string
+	Input    string
+	Outcomes []Outcome
+}
+
+// Tests returns the tests of the suite.
+func Tests() []*Test {
+	return []*Test{
+		{
+			Name: "brackets",
+			Code: source("brackets"),
+			Outcomes: []Outcome{
+				{Output: "A\n", Behavior: "nested and skipped loops work"},
+			},
+		},
+		{
+			Name: "obscure",
+			Code: source("obscure"),
+			Outcomes: []Outcome{
+				{Output: "H\n", Behavior: "initial loops and comments are handled"},
+			},
+		},
+		{
+			Name: "cell-size",
+			Code: source("cellsize"),
+			Outcomes: []Outcome{
+				{Output: "8\n", Behavior: "8-bit cells"},
+				{Output: "16\n", Behavior: "16-bit cells"},
+				{Output: "32\n", Behavior: "32-bit cells"},
+			},
+		},
+		{
+			Name:  "eof",
+			Code:  source("eof"),
+			Input: "\n",
+			Outcomes: []Outcome{
+				{Output: "LK\nLK\n", Behavior: "EOF leaves the cell unchanged"},
+				{Output: "LB\nLB\n", Behavior: "EOF sets the cell to 0"},
+				{Output: "LA\nLA\n", Behavior: "EOF sets the cell to -1"},
+			},
+		},
+		{
+			Name: "tape-length",
+			Code: source("tapelength"),
+			Outcomes: []Outcome{
+				{Output: "#\n", Behavior: "at least 30000 cells"},
+				{Err: interpreter.ErrTapeBounds, Behavior: "fewer than 30000 cells"},
+			},
+		},
+		{
+			Name: "tape-left",
+			Code: source("tapeleft"),
+			Outcomes: []Outcome{
+				{Err: interpreter.ErrTapeBounds, Behavior: "using a cell left of the first one fails"},
+				{Output: "1\n", Behavior: "using a cell left of the first one is allowed"},
+			},
+		},
+	}
+}
+
+func source(name string) string {
+	b, err := sources.ReadFile("tests/" + name + ".b")
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+// Status tells how the behavior of an interpreter compares to the outcomes of a test.
+type Status string
+
+const (
+	// Pass is the behavior most programs assume.
+	Pass Status = "pass"
+	// Differ is another known behavior.
+	Differ Status = "differ"
+	// Fail is an unexpected output or error.
+	Fail Status = "fail"
+)
+
+// Result is the outcome of a test for an interpreter configuration.
+type Result struct {
+	Test     *Test
+	Status   Status
+	Behavior string
+	Output   string
+	Err      error
+}
+
+// Report is the result of every test of the suite.
+type Report struct {
+	Results []Result
+}
+
+// Run runs the tests of the suite, opts configure the interpreters.
+// reading past the end of the input is not an error.
+func Run(opts ...interpreter.Option) *Report {
+	r := &Report{}
+	for _, t := range Tests() {
+		r.Results = append(r.Results, t.Run(opts...))
+	}
+	return r
+}
+
+// Run runs the test, opts configure the interpreter.
+func (t *Test) Run(opts ...interpreter.Option) Result {
+	var out bytes.Buffer
+	opts = append([]interpreter.Option{interpreter.WithMaxSteps(MaxSteps)}, opts...)
+	err := interpreter.NewInterpreter(strings.NewReader(t.Input), &out, strings.NewReader(t.Code), opts...).Run()
+	if err == io.EOF {
+		err = nil
+	}
+
+	res := Result{Test: t, Status: Fail, Output: out.String(), Err: err}
+	for i, o := range t.Outcomes {
+		if (o.Err == nil && err == nil && o.Output == res.Output) || (o.Err != nil && errors.Is(err, o.Err)) {
+			res.Status = Differ
+			if i == 0 {
+				res.Status = Pass
+			}
+			res.Behavior = o.Behavior
+			return res
+		}
+	}
+	if err != nil {
+		res.Behavior = err.Error()
+	} else {
+		res.Behavior = fmt.Sprintf("unexpected output %q", res.Output)
+	}
+	return res
+}
+
+// Passed reports whether no test has failed, behaviors which differ from the common ones are allowed.
+func (r *Report) Passed() bool {
+	for _, res := range r.Results {
+		if res.Status == Fail {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the report as a table with a line per test.
+func (r *Report) String() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	for _, res := range r.Results {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", res.Test.Name, res.Status, res.Behavior)
+	}
+	w.Flush()
+	return b.String()
+}
@@ -0,0 +1,84 @@
+package conformance_test
+
+import (
+	"strings"
+	"testing"
+
+	interpreter "github.com/momaee/WL"
+	"github.com/momaee/WL/conformance"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []interpreter.Option
+		expected map[string]string
+	}{
+		{
+			name: "default",
+			expected: map[string]string{
+				"brackets":    "nested and skipped loops work",
+				"obscure":     "initial loops and comments are handled",
+				"cell-size":   "8-bit cells",
+				"eof":         "EOF leaves the cell unchanged",
+				"tape-length": "fewer than 30000 cells",
+				"tape-left":   "using a cell left of the first one fails",
+			},
+		},
+		{
+			name: "16-bit cells, EOF zero",
+			opts: []interpreter.Option{interpreter.WithCellSize(16), interpreter.WithEOF(interpreter.EOFZero)},
+			expected: map[string]string{
+				"cell-size": "16-bit cells",
+				"eof":       "EOF sets the cell to 0",
+			},
+		},
+		{
+			name: "32-bit cells, EOF minus one, wrapping tape",
+			opts: []interpreter.Option{interpreter.WithCellSize(32), interpreter.WithEOF(interpreter.EOFMinusOne), interpreter.WithWrappingTape()},
+			expected: map[string]string{
+				"cell-size": "32-bit cells",
+				"eof":       "EOF sets the cell to -1",
+				"tape-left": "using a cell left of the first one is allowed",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := conformance.Run(tt.opts...)
+			for _, res := range report.Results {
+				if expected, ok := tt.expected[res.Test.Name]; ok && res.Behavior != expected {
+					t.Errorf("%s: expected %q got %q (%s)", res.Test.Name, expected, res.Behavior, res.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	report := conformance.Run()
+	if !report.Passed() {
+		t.Errorf("expected the default configuration to pass\n%v", report)
+	}
+
+	expected := `brackets     pass    nested and skipped loops work
+obscure      pass    initial loops and comments are handled
+cell-size    pass    8-bit cells
+eof          pass    EOF leaves the cell unchanged
+tape-length  differ  fewer than 30000 cells
+tape-left    pass    using a cell left of the first one fails
+`
+	if s := report.String(); s != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, s)
+	}
+}
+
+func TestTest_Run(t *testing.T) {
+	// a test stopped by the step limit fails
+	test := conformance.Tests()[0]
+	res := test.Run(interpreter.WithMaxSteps(10))
+	if res.Status != conformance.Fail || !strings.Contains(res.Behavior, "step limit") {
+		t.Errorf("expected the step limit to fail the test got %+v", res)
+	}
+}
@@ -0,0 +1,5 @@
+Loops nested ten deep and a skipped loop containing every command
++[[[[[[[[[[-]]]]]]]]]][[+]>[<.,>-]<]
+Three nested loops counting to 64 then printing A
+++++++++[>++++++++[>+<-]<-]>>+.
+<<++++++++++.
@@ -0,0 +1,8 @@
+Computes 256 and prints 8 if it is zero
+otherwise computes 65536 and prints 16 if it is zero and 32 if not
+++++++++[>++++++++<-]>[<++++>-]>+<<
+[>>-<<[>++++++++<-]>[<++++++++>-]<[>++++<-]>[<+>-]>>+<<<[>>>-<<<[-]>>>>+<<<<]]
+>>[>>>>+++++++[<++++++++>-]<.[-]<<<-]
+>[>>>+++++++[<+++++++>-]<.+++++.[-]<<-]
+>[>>++++++[<++++++++>-]<+++.-.[-]<-]
+>++++++++++.[-]
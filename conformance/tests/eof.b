@@ -0,0 +1,4 @@
+Reads a newline and then reads again at the end of the input
+prints LB and a newline twice if the cell is set to zero
+LA if it is set to minus one and LK if it is left unchanged
+>,>+++++++++,>+++++++++++[<++++++<++++++<+>>>-]<<.>.<<-.>.>.<<.
@@ -0,0 +1,4 @@
+Tests several obscure problems: an initial loop that is skipped and
+characters that are not commands; prints an H and a newline
+[]++++++++++[>>+>+>++++++[<<+<+++>>>-]<<<<-]
+"A*$";?@![#>>+<<]>[>>]<<<<[>++<[-]]>.>.
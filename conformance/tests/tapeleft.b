@@ -0,0 +1,3 @@
+Moves left of the first cell and adds 7 times 7 to the first cell from there
+prints 1 and a newline if that is allowed
+<+++++++[>+++++++<-]>.[-]++++++++++.
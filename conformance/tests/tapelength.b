@@ -0,0 +1,3 @@
+Goes to cell 30000 and prints a # and a newline from there
+++++[>++++++<-]>[>+++++>+++++++<<-]>>++++<[[>[[>>+<<-]<]>>>-]>-[>+>+<<-]>]
++++++[>+++++++<<++>-]>.<<.
@@ -0,0 +1,206 @@
+// Package dialect supports trivial substitutions of Brainfuck,
+// languages like Ook! which only rename the eight commands.
+package dialect
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/momaee/WL/lexer"
+	"github.com/momaee/WL/token"
+)
+
+// Dialect maps each Brainfuck command to the symbol used for it.
+// a space inside a symbol matches any run of whitespace in the source.
+// Separator is written between two commands when translating into the dialect.
+type Dialect struct {
+	Name      string
+	Commands  map[token.Type]string
+	Separator string
+}
+
+// built-in dialects
+var (
+	Brainfuck = &Dialect{
+		Name: "brainfuck",
+		Commands: map[token.Type]string{
+			token.RightToken:        ">",
+			token.LeftToken:         "<",
+			token.PlusToken:         "+",
+			token.MinusToken:        "-",
+			token.PrintToken:        ".",
+			token.ReadToken:         ",",
+			token.LeftBracketToken:  "[",
+			token.RightBracketToken: "]",
+		},
+	}
+
+	Ook = &Dialect{
+		Name: "ook",
+		Commands: map[token.Type]string{
+			token.RightToken:        "Ook. Ook?",
+			token.LeftToken:         "Ook? Ook.",
+			token.PlusToken:         "Ook. Ook.",
+			token.MinusToken:        "Ook! Ook!",
+			token.PrintToken:        "Ook! Ook.",
+			token.ReadToken:         "Ook. Ook!",
+			token.LeftBracketToken:  "Ook! Ook?",
+			token.RightBracketToken: "Ook? Ook!",
+		},
+		Separator: " ",
+	}
+
+	Blub = &Dialect{
+		Name: "blub",
+		Commands: map[token.Type]string{
+			token.RightToken:        "Blub. Blub?",
+			token.LeftToken:         "Blub? Blub.",
+			token.PlusToken:         "Blub. Blub.",
+			token.MinusToken:        "Blub! Blub!",
+			token.PrintToken:        "Blub! Blub.",
+			token.ReadToken:         "Blub. Blub!",
+			token.LeftBracketToken:  "Blub! Blub?",
+			token.RightBracketToken: "Blub? Blub!",
+		},
+		Separator: " ",
+	}
+
+	Alphuck = &Dialect{
+		Name: "alphuck",
+		Commands: map[token.Type]string{
+			token.RightToken:        "a",
+			token.LeftToken:         "c",
+			token.PlusToken:         "e",
+			token.MinusToken:        "i",
+			token.PrintToken:        "j",
+			token.ReadToken:         "o",
+			token.LeftBracketToken:  "p",
+			token.RightBracketToken: "s",
+		},
+	}
+
+	Pikalang = &Dialect{
+		Name: "pikalang",
+		Commands: map[token.Type]string{
+			token.RightToken:        "pipi",
+			token.LeftToken:         "pichu",
+			token.PlusToken:         "pi",
+			token.MinusToken:        "ka",
+			token.PrintToken:        "pikachu",
+			token.ReadToken:         "pikapi",
+			token.LeftBracketToken:  "pika",
+			token.RightBracketToken: "chu",
+		},
+		Separator: " ",
+	}
+)
+
+// Dialects lists the built-in dialects by name.
+var Dialects = map[string]*Dialect{
+	Brainfuck.Name: Brainfuck,
+	Ook.Name:       Ook,
+	Blub.Name:      Blub,
+	Alphuck.Name:   Alphuck,
+	Pikalang.Name:  Pikalang,
+}
+
+// Validate returns an error if a command is missing or two commands share a symbol.
+func (d *Dialect) Validate() error {
+	seen := map[string]token.Type{}
+	for t := token.LeftToken; t <= token.RightBracketToken; t++ {
+		symbol := d.Commands[t]
+		if symbol == "" {
+			return fmt.Errorf("dialect %s: missing symbol for %s", d.Name, token.Builtin(t).Value)
+		}
+		if other, ok := seen[symbol]; ok {
+			return fmt.Errorf("dialect %s: symbol %q used for %s and %s", d.Name, symbol, token.Builtin(other).Value, token.Builtin(t).Value)
+		}
+		seen[symbol] = t
+	}
+	return nil
+}
+
+// Symbols returns the symbols of the dialect mapped to the tokens of the Brainfuck commands.
+func (d *Dialect) Symbols() map[string]*token.Token {
+	symbols := make(map[string]*token.Token, len(d.Commands))
+	for t, symbol := range d.Commands {
+		symbols[symbol] = token.Builtin(t)
+	}
+	return symbols
+}
+
+// Scanner returns a lexer for the dialect,
+// the tokens of the commands are the ones of Brainfuck so they can be parsed as usual.
+func (d *Dialect) Scanner(r io.Reader) lexer.LexScanner {
+	return lexer.NewScanner(r, lexer.WithSymbols(d.Symbols()))
+}
+
+// Translate reads a program in the dialect from and writes it in the dialect to.
+// comments are preserved, except for the parts which would be read as commands in to.
+// the separator of from between two commands is replaced with the one of to.
+func Translate(w io.Writer, r io.Reader, from, to *Dialect) error {
+	for _, d := range []*Dialect{from, to} {
+		if err := d.Validate(); err != nil {
+			return err
+		}
+	}
+
+	var (
+		out     bytes.Buffer
+		comment strings.Builder
+		command bool // the last thing written is a command
+	)
+	flush := func() {
+		if text := sanitize(comment.String(), to); text != "" {
+			out.WriteString(text)
+			command = false
+		}
+		comment.Reset()
+	}
+
+	l := from.Scanner(r)
+	for {
+		tok := l.Scan()
+		switch tok.Tok {
+		case token.EOFToken:
+			flush()
+			_, err := w.Write(out.Bytes())
+			return err
+
+		case token.IllegalToken, token.WhitespaceToken:
+			comment.WriteString(tok.Value)
+
+		default:
+			// the separator between two commands is not a comment
+			if command && from.Separator != "" && comment.String() == from.Separator {
+				comment.Reset()
+			}
+			flush()
+			if command {
+				out.WriteString(to.Separator)
+			}
+			out.WriteString(to.Commands[tok.Tok])
+			command = true
+		}
+	}
+}
+
+// sanitize removes the parts of text which d would read as commands.
+func sanitize(text string, d *Dialect) string {
+	if text == "" {
+		return ""
+	}
+	var b strings.Builder
+	l := d.Scanner(strings.NewReader(text))
+	for {
+		tok := l.Scan()
+		switch tok.Tok {
+		case token.EOFToken:
+			return b.String()
+		case token.IllegalToken, token.WhitespaceToken:
+			b.WriteString(tok.Value)
+		}
+	}
+}
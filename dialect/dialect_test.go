@@ -0,0 +1,97 @@
+package dialect_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/momaee/WL/dialect"
+	"github.com/momaee/WL/lexer"
+	"github.com/momaee/WL/parser"
+	"github.com/momaee/WL/token"
+)
+
+const helloWorld = "++++++++[>++++[>++>+++>+++>+<<<<-]>+>+>->>+[<]<-]>>.>---.+++++++..+++.>>.<-.<.+++.------.--------.>>+.>++."
+
+// parse returns the commands of the program, with folded instructions expanded.
+func parse(l lexer.LexScanner) string {
+	var out strings.Builder
+	for _, in := range parser.NewParser(l).Parse() {
+		if in.T.Tok == token.LeftBracketToken || in.T.Tok == token.RightBracketToken {
+			out.WriteString(in.T.Value)
+		} else {
+			out.WriteString(strings.Repeat(in.T.Value, in.C))
+		}
+	}
+	return out.String()
+}
+
+func TestDialects_Validate(t *testing.T) {
+	for name, d := range dialect.Dialects {
+		if err := d.Validate(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	broken := &dialect.Dialect{Name: "broken", Commands: map[token.Type]string{token.PlusToken: "a", token.MinusToken: "a"}}
+	if err := broken.Validate(); err == nil {
+		t.Errorf("expected error for incomplete dialect")
+	}
+}
+
+func TestScanner(t *testing.T) {
+	// Ook pairs may be split over lines
+	src := "Ook. Ook.\nOok. Ook?   Ook!\n\tOok!  comment"
+	got := parse(dialect.Ook.Scanner(strings.NewReader(src)))
+	if got != "+>-" {
+		t.Errorf("expected +>- got %s", got)
+	}
+
+	src = "pi pi pika pipi pikachu pichu ka chu"
+	got = parse(dialect.Pikalang.Scanner(strings.NewReader(src)))
+	if got != "++[>.<-]" {
+		t.Errorf("expected ++[>.<-] got %s", got)
+	}
+}
+
+func TestTranslate_RoundTrip(t *testing.T) {
+	expected := parse(lexer.NewScanner(strings.NewReader(helloWorld)))
+
+	for name, d := range dialect.Dialects {
+		var translated bytes.Buffer
+		if err := dialect.Translate(&translated, strings.NewReader(helloWorld), dialect.Brainfuck, d); err != nil {
+			t.Fatal(err)
+		}
+		if got := parse(d.Scanner(bytes.NewReader(translated.Bytes()))); got != expected {
+			t.Errorf("%s: translation changed the program:\n%s", name, translated.String())
+		}
+
+		var back bytes.Buffer
+		if err := dialect.Translate(&back, &translated, d, dialect.Brainfuck); err != nil {
+			t.Fatal(err)
+		}
+		if back.String() != helloWorld {
+			t.Errorf("%s: expected %s got %s", name, helloWorld, back.String())
+		}
+	}
+}
+
+func TestTranslate_Comments(t *testing.T) {
+	var out bytes.Buffer
+	src := "Say hi. Ook. Ook. Ook! Ook. done"
+	if err := dialect.Translate(&out, strings.NewReader(src), dialect.Ook, dialect.Brainfuck); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "Say hi +. done" {
+		t.Errorf("expected %q got %q", "Say hi +. done", out.String())
+	}
+
+	out.Reset()
+	if err := dialect.Translate(&out, strings.NewReader("add +[-] end"), dialect.Brainfuck, dialect.Ook); err != nil {
+		t.Fatal(err)
+	}
+	expected := "add Ook. Ook. Ook! Ook? Ook! Ook! Ook? Ook! end"
+	if out.String() != expected {
+		t.Errorf("expected %q got %q", expected, out.String())
+	}
+}
@@ -0,0 +1,291 @@
+// Package difftest runs a program through several execution engines and reports the first difference between them.
+//
+// The first engine is the reference, every other engine must produce the same output, tape and error.
+// Engines count steps differently, runs in which the reference exceeds MaxSteps are not compared.
+package difftest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	interpreter "github.com/momaee/WL"
+	"github.com/momaee/WL/boolfuck"
+	"github.com/momaee/WL/format"
+	"github.com/momaee/WL/lexer"
+	"github.com/momaee/WL/lint"
+	"github.com/momaee/WL/parser"
+	"github.com/momaee/WL/token"
+)
+
+// MaxSteps is the step limit of the runs, unless set by interpreter.WithMaxSteps.
+const MaxSteps = 1000000
+
+// Outcome is what a run of a program left behind.
+// Tape is nil if the engine has no tape comparable to the one of the interpreter.
+// reading past the end of the input is not an error.
+type Outcome struct {
+	Output []byte
+	Tape   []int
+	Err    error
+}
+
+// Engine runs code with input, opts configure the interpreter.
+type Engine struct {
+	Name string
+	Run  func(code string, input []byte, opts []interpreter.Option) Outcome
+}
+
+// Engines are the engines compared by Check, Run is the reference.
+var Engines = []Engine{Run, Program, Reader, Resumable, Minified, Formatted, Linted}
+
+// the engines
+var (
+	// Run is the Run loop of an interpreter.
+	Run = Engine{Name: "run", Run: run}
+
+	// Program runs a compiled program.
+	Program = Engine{Name: "program", Run: program}
+
+	// Reader reads the output of an interpreter.Reader.
+	Reader = Engine{Name: "reader", Run: reader}
+
+	// Resumable runs an interpreter.Resumable, fed with the whole input.
+	Resumable = Engine{Name: "resumable", Run: resumable}
+
+	// Minified runs the code minified by format.Minify.
+	Minified = Engine{Name: "minified", Run: minified}
+
+	// Formatted runs the code pretty-printed by format.Source.
+	Formatted = Engine{Name: "formatted", Run: formatted}
+
+	// Linted runs the code with the fixes of lint.Lint applied.
+	Linted = Engine{Name: "linted", Run: linted}
+
+	// Boolfuck runs the code converted by boolfuck.FromBrainfuck in Boolfuck mode.
+	// its tape is not compared, it reads zero at the end of the input and its tape is unbounded,
+	// so it only agrees with the others on programs which neither read past the input nor leave the tape.
+	Boolfuck = Engine{Name: "boolfuck", Run: runBoolfuck}
+)
+
+// Divergence is the first difference between the reference engine and another one.
+// Kind is "output", "tape" or "error", Offset is the byte of the output or the cell of the tape.
+type Divergence struct {
+	Engine string
+	Kind   string
+	Offset int
+	Want   string
+	Got    string
+}
+
+func (d *Divergence) Error() string {
+	if d.Kind == "error" {
+		return fmt.Sprintf("%s: error %s, expected %s", d.Engine, d.Got, d.Want)
+	}
+	return fmt.Sprintf("%s: %s at %d is %s, expected %s", d.Engine, d.Kind, d.Offset, d.Got, d.Want)
+}
+
+// Check runs code with input through Engines, see Compare.
+func Check(code string, input []byte, opts ...interpreter.Option) *Divergence {
+	return Compare(Engines, code, input, opts...)
+}
+
+// Compare runs code with input through every engine and returns the first divergence from the first engine,
+// nil if they all agree or if the first engine exceeds the step limit.
+func Compare(engines []Engine, code string, input []byte, opts ...interpreter.Option) *Divergence {
+	if len(engines) == 0 {
+		return nil
+	}
+	opts = append([]interpreter.Option{interpreter.WithMaxSteps(MaxSteps)}, opts...)
+	ref := engines[0].Run(code, input, opts)
+	if errors.Is(ref.Err, interpreter.ErrStepLimit) {
+		return nil
+	}
+	for _, e := range engines[1:] {
+		if d := diff(ref, e.Run(code, input, opts)); d != nil {
+			d.Engine = e.Name
+			return d
+		}
+	}
+	return nil
+}
+
+// Generate turns data into a program with balanced brackets, every byte selects a command.
+func Generate(data []byte) string {
+	const commands = "+-<>[].,"
+	var b strings.Builder
+	depth := 0
+	for _, c := range data {
+		cmd := commands[int(c)%len(commands)]
+		switch cmd {
+		case '[':
+			depth++
+		case ']':
+			if depth == 0 {
+				continue
+			}
+			depth--
+		}
+		b.WriteByte(cmd)
+	}
+	b.WriteString(strings.Repeat("]", depth))
+	return b.String()
+}
+
+// diff returns the first difference of got from want.
+func diff(want, got Outcome) *Divergence {
+	if i, ok := firstDiff(len(want.Output), len(got.Output), func(i int) bool { return want.Output[i] == got.Output[i] }); !ok {
+		return &Divergence{Kind: "output", Offset: i, Want: byteAt(want.Output, i), Got: byteAt(got.Output, i)}
+	}
+	if want.Tape != nil && got.Tape != nil {
+		if i, ok := firstDiff(len(want.Tape), len(got.Tape), func(i int) bool { return want.Tape[i] == got.Tape[i] }); !ok {
+			return &Divergence{Kind: "tape", Offset: i, Want: cellAt(want.Tape, i), Got: cellAt(got.Tape, i)}
+		}
+	}
+	if class(want.Err) != class(got.Err) {
+		return &Divergence{Kind: "error", Want: errString(want.Err), Got: errString(got.Err)}
+	}
+	return nil
+}
+
+// firstDiff returns the first index at which two sequences of length n and m differ, ok is true if they are equal.
+func firstDiff(n, m int, equal func(int) bool) (int, bool) {
+	i := 0
+	for i < n && i < m && equal(i) {
+		i++
+	}
+	return i, i == n && i == m
+}
+
+func byteAt(p []byte, i int) string {
+	if i >= len(p) {
+		return "end of output"
+	}
+	return fmt.Sprintf("%q", p[i])
+}
+
+func cellAt(tape []int, i int) string {
+	if i >= len(tape) {
+		return "end of tape"
+	}
+	return fmt.Sprint(tape[i])
+}
+
+func errString(err error) string {
+	if err == nil {
+		return "none"
+	}
+	return fmt.Sprintf("%q", err.Error())
+}
+
+// sentinels are the errors which are the same for every engine, except for the positions in their messages.
+var sentinels = []error{
+	interpreter.ErrStepLimit,
+	interpreter.ErrTapeBounds,
+	interpreter.ErrCallDepth,
+	interpreter.ErrUndefinedProcedure,
+}
+
+// class returns the kind of err, the messages of other errors depend on the engine.
+func class(err error) string {
+	if err == nil {
+		return ""
+	}
+	for _, s := range sentinels {
+		if errors.Is(err, s) {
+			return s.Error()
+		}
+	}
+	return "error"
+}
+
+// outcome returns the outcome of a run which has ended with err.
+func outcome(out []byte, err error, cells func(int) int) Outcome {
+	if err == io.EOF {
+		err = nil
+	}
+	o := Outcome{Output: out, Err: err}
+	if cells != nil {
+		o.Tape = make([]int, token.MemorySize)
+		for i := range o.Tape {
+			o.Tape[i] = cells(i)
+		}
+	}
+	return o
+}
+
+func run(code string, input []byte, opts []interpreter.Option) Outcome {
+	var out bytes.Buffer
+	bfm := interpreter.NewInterpreter(bytes.NewReader(input), &out, strings.NewReader(code), opts...)
+	err := bfm.Run()
+	return outcome(out.Bytes(), err, bfm.GetValueInMemory)
+}
+
+func program(code string, input []byte, opts []interpreter.Option) Outcome {
+	p, err := interpreter.Compile(strings.NewReader(code), opts...)
+	if err != nil {
+		return outcome(nil, err, nil)
+	}
+	var out bytes.Buffer
+	bfm := p.NewInterpreter(bytes.NewReader(input), &out)
+	err = bfm.Run()
+	return outcome(out.Bytes(), err, bfm.GetValueInMemory)
+}
+
+func reader(code string, input []byte, opts []interpreter.Option) Outcome {
+	out, err := io.ReadAll(interpreter.NewReader(bytes.NewReader(input), strings.NewReader(code), opts...))
+	return outcome(out, err, nil)
+}
+
+func resumable(code string, input []byte, opts []interpreter.Option) Outcome {
+	r := interpreter.NewResumable(strings.NewReader(code), opts...)
+	r.Feed(input)
+	r.CloseInput()
+
+	var out []byte
+	for {
+		state, err := r.Run()
+		out = append(out, r.Output()...)
+		if state == interpreter.Done || err != nil {
+			return outcome(out, err, r.GetValueInMemory)
+		}
+	}
+}
+
+func minified(code string, input []byte, opts []interpreter.Option) Outcome {
+	src, err := format.Minify(strings.NewReader(code))
+	if err != nil {
+		return outcome(nil, err, nil)
+	}
+	return run(string(src), input, opts)
+}
+
+func formatted(code string, input []byte, opts []interpreter.Option) Outcome {
+	src, err := format.Source(strings.NewReader(code), format.DefaultOptions)
+	if err != nil {
+		return outcome(nil, err, nil)
+	}
+	return run(string(src), input, opts)
+}
+
+func linted(code string, input []byte, opts []interpreter.Option) Outcome {
+	inst := parser.NewParser(lexer.NewScanner(strings.NewReader(code))).Parse()
+	src := lint.Apply([]byte(code), lint.Lint(inst))
+	return run(string(src), input, opts)
+}
+
+// boolfuckSteps is the number of Boolfuck steps allowed per Brainfuck step.
+const boolfuckSteps = 100
+
+func runBoolfuck(code string, input []byte, opts []interpreter.Option) Outcome {
+	var src bytes.Buffer
+	if err := boolfuck.FromBrainfuck(&src, strings.NewReader(code)); err != nil {
+		return outcome(nil, err, nil)
+	}
+	var out bytes.Buffer
+	opts = append(opts[:len(opts):len(opts)], interpreter.WithMode(interpreter.Boolfuck), interpreter.WithMaxSteps(MaxSteps*boolfuckSteps))
+	err := interpreter.NewInterpreter(bytes.NewReader(input), &out, &src, opts...).Run()
+	return outcome(out.Bytes(), err, nil)
+}
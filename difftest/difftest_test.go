@@ -0,0 +1,115 @@
+package difftest_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	interpreter "github.com/momaee/WL"
+	"github.com/momaee/WL/difftest"
+)
+
+const hello = "++++++++[>++++[>++>+++>+++>+<<<<-]>+>+>->>+[<]<-]>>.>---.+++++++..+++.>>.<-.<.+++.------.--------.>>+.>++."
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  string
+		input string
+		opts  []interpreter.Option
+	}{
+		{"hello", hello, "", nil},
+		{"cat", ",[.,]", "abc\x00", nil},
+		{"read past the input", ",,,.", "a", nil},
+		{"comments", "add [two] + + and print .", "", nil},
+		{"tape bounds", "+<.", "", nil},
+		{"pass the tape bounds", "<>+", "", nil},
+		{"add outside of the tape", "<-+", "", nil},
+		{"cancel outside of the tape", "<+->", "", nil},
+		{"add and return", "<+->+.", "", nil},
+		{"loop outside of the tape", "<+-[]", "", nil},
+		{"step limit", "+[]", "", nil},
+		{"16 bits", "-.>" + strings.Repeat("+", 300) + ".", "", []interpreter.Option{interpreter.WithCellSize(16)}},
+		{"wrapping tape", "<<+.>><>", "", []interpreter.Option{interpreter.WithWrappingTape()}},
+		{"unbalanced", "[[]", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if d := difftest.Check(tt.code, []byte(tt.input), tt.opts...); d != nil {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	if d := difftest.Compare([]difftest.Engine{difftest.Run, difftest.Boolfuck}, hello, nil); d != nil {
+		t.Error(d)
+	}
+
+	// an engine printing one byte too many
+	broken := difftest.Engine{Name: "broken", Run: func(code string, input []byte, opts []interpreter.Option) difftest.Outcome {
+		o := difftest.Run.Run(code, input, opts)
+		o.Output = append(o.Output, '!')
+		return o
+	}}
+	d := difftest.Compare([]difftest.Engine{difftest.Run, broken}, "+++.", nil)
+	expected := &difftest.Divergence{Engine: "broken", Kind: "output", Offset: 1, Want: "end of output", Got: "'!'"}
+	if d == nil || *d != *expected {
+		t.Errorf("expected %v got %v", expected, d)
+	}
+
+	if s := d.Error(); s != "broken: output at 1 is '!', expected end of output" {
+		t.Errorf("unexpected message %q", s)
+	}
+
+	// an engine which leaves a different tape
+	tape := difftest.Engine{Name: "tape", Run: func(code string, input []byte, opts []interpreter.Option) difftest.Outcome {
+		o := difftest.Run.Run(code, input, opts)
+		o.Tape[1]++
+		return o
+	}}
+	d = difftest.Compare([]difftest.Engine{difftest.Run, tape}, "+++.", nil)
+	expected = &difftest.Divergence{Engine: "tape", Kind: "tape", Offset: 1, Want: "0", Got: "1"}
+	if d == nil || *d != *expected {
+		t.Errorf("expected %v got %v", expected, d)
+	}
+	if s := d.Error(); s != "tape: tape at 1 is 1, expected 0" {
+		t.Errorf("unexpected message %q", s)
+	}
+
+	// an engine which ignores the ends of the tape
+	bounds := difftest.Engine{Name: "bounds", Run: func(code string, input []byte, opts []interpreter.Option) difftest.Outcome {
+		return difftest.Run.Run(code, input, append(opts, interpreter.WithWrappingTape()))
+	}}
+	d = difftest.Compare([]difftest.Engine{difftest.Run, bounds}, "<[]", nil)
+	if s := d.Error(); s != `bounds: error none, expected "1:2: cell -1: pointer outside of the tape"` {
+		t.Errorf("unexpected message %q", s)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		data     []byte
+		expected string
+	}{
+		{[]byte{0, 1, 2, 3}, "+-<>"},
+		{[]byte{4, 4, 5}, "[[]]"},
+		{[]byte{5, 6, 7}, ".,"},
+	}
+	for _, tt := range tests {
+		if p := difftest.Generate(tt.data); p != tt.expected {
+			t.Errorf("Generate(%v) = %q, expected %q", tt.data, p, tt.expected)
+		}
+	}
+
+	for i := 0; i < 200; i++ {
+		data := bytes.Repeat([]byte{byte(i), byte(i * 7), byte(i * 13)}, 10)
+		if d := difftest.Check(difftest.Generate(data), data); d != nil {
+			t.Errorf("%q: %v", difftest.Generate(data), d)
+		}
+		if d := difftest.Check(difftest.Generate(data), data, interpreter.WithWrappingTape()); d != nil {
+			t.Errorf("%q with a wrapping tape: %v", difftest.Generate(data), d)
+		}
+	}
+}
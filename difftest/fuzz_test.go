@@ -0,0 +1,29 @@
+//go:build go1.18
+// +build go1.18
+
+package difftest_test
+
+import (
+	"testing"
+
+	interpreter "github.com/momaee/WL"
+	"github.com/momaee/WL/difftest"
+)
+
+// FuzzEngines runs random programs through every engine, with the default tape and with a wrapping one.
+func FuzzEngines(f *testing.F) {
+	f.Add([]byte("\x00\x04\x02\x05\x06\x01"), []byte("abc"))
+	f.Add([]byte("\x00\x00\x04\x03\x00\x02\x01\x05\x03\x06"), []byte{})
+	f.Add([]byte("\x02\x03\x00"), []byte{})
+	f.Add([]byte("\x02\x01\x00"), []byte{})
+	f.Add([]byte("\x02\x00\x01\x03"), []byte{})
+	f.Fuzz(func(t *testing.T, data, input []byte) {
+		code := difftest.Generate(data)
+		if d := difftest.Check(code, input); d != nil {
+			t.Fatalf("%q: %v", code, d)
+		}
+		if d := difftest.Check(code, input, interpreter.WithWrappingTape()); d != nil {
+			t.Fatalf("%q with a wrapping tape: %v", code, d)
+		}
+	})
+}
@@ -0,0 +1,122 @@
+// Package ebf implements the commands of Extended Brainfuck Type I as an operator pack.
+//
+//	@  ends the program
+//	$  copies the current cell to the storage
+//	!  copies the storage to the current cell
+//	}  shifts the current cell one bit to the right
+//	{  shifts the current cell one bit to the left
+//	~  bitwise NOT of the current cell
+//	^  bitwise XOR of the current cell and the storage
+//	&  bitwise AND of the current cell and the storage
+//	|  bitwise OR of the current cell and the storage
+//
+// The results are stored in the current cell, wrapped around at the cell size of the interpreter.
+//
+// Like every operator added through an interpreter, the operators of a pack are registered globally in token.AllTokens:
+// while a pack is registered every interpreter lexes its symbols, also the ones it was not registered for,
+// and '{' and '}' are no longer comments anywhere. Unregister the pack once the program has run.
+package ebf
+
+import (
+	"fmt"
+
+	interpreter "github.com/momaee/WL"
+)
+
+// Pack holds the storage register shared by the operators.
+// a Pack should be registered for one interpreter at a time, and only one Pack at a time, as the registration is global.
+type Pack struct {
+	Storage int
+}
+
+// New returns a Pack with an empty storage.
+func New() *Pack {
+	return &Pack{}
+}
+
+// Operators returns the operators of the pack by symbol.
+// c is the number of repetitions of a symbol folded by the parser.
+func (p *Pack) Operators() map[rune]interpreter.ContextOperator {
+	return map[rune]interpreter.ContextOperator{
+		'@': func(c int, ctx *interpreter.Context) error {
+			ctx.IP = ctx.Len
+			return nil
+		},
+		'$': func(c int, ctx *interpreter.Context) error {
+			p.Storage = cell(ctx)
+			return nil
+		},
+		'!': func(c int, ctx *interpreter.Context) error {
+			set(ctx, p.Storage)
+			return nil
+		},
+		'}': func(c int, ctx *interpreter.Context) error {
+			set(ctx, cell(ctx)>>uint(c))
+			return nil
+		},
+		'{': func(c int, ctx *interpreter.Context) error {
+			set(ctx, cell(ctx)<<uint(c))
+			return nil
+		},
+		'~': func(c int, ctx *interpreter.Context) error {
+			if c%2 == 1 {
+				set(ctx, ^cell(ctx))
+			}
+			return nil
+		},
+		'^': func(c int, ctx *interpreter.Context) error {
+			if c%2 == 1 {
+				set(ctx, cell(ctx)^p.Storage)
+			}
+			return nil
+		},
+		'&': func(c int, ctx *interpreter.Context) error {
+			set(ctx, cell(ctx)&p.Storage)
+			return nil
+		},
+		'|': func(c int, ctx *interpreter.Context) error {
+			set(ctx, cell(ctx)|p.Storage)
+			return nil
+		},
+	}
+}
+
+// Register adds the operators of the pack through bfm, which adds them for every interpreter, see token.AllTokens.
+// the storage is only meant for bfm. if a symbol is already taken none of the operators are added.
+func (p *Pack) Register(bfm interpreter.Interpreter) error {
+	var added []rune
+	for symbol, op := range p.Operators() {
+		if err := bfm.AddContextOperator(symbol, op); err != nil {
+			for _, r := range added {
+				_ = bfm.RemoveOperator(r)
+			}
+			return fmt.Errorf("ebf: %w", err)
+		}
+		added = append(added, symbol)
+	}
+	return nil
+}
+
+// Unregister removes the operators of the pack, for every interpreter.
+func (p *Pack) Unregister(bfm interpreter.Interpreter) {
+	for symbol := range p.Operators() {
+		_ = bfm.RemoveOperator(symbol)
+	}
+}
+
+func cell(ctx *interpreter.Context) int {
+	return ctx.Memory.Cell[ctx.Memory.Cursor]
+}
+
+// set stores v in the current cell, keeping the bits of the cell size.
+func set(ctx *interpreter.Context, v int) {
+	switch ctx.CellSize {
+	case 16:
+		v = int(uint16(v))
+	case 32:
+		v = int(uint32(v))
+	default:
+		v = int(uint8(v))
+	}
+	ctx.Memory.Cell[ctx.Memory.Cursor] = v
+}
@@ -0,0 +1,93 @@
+package ebf_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	interpreter "github.com/momaee/WL"
+	"github.com/momaee/WL/ebf"
+)
+
+func TestPack(t *testing.T) {
+	tests := []struct {
+		code     string
+		cell     int
+		expected int
+	}{
+		{"+++++$>!", 1, 5},
+		{"+++}", 0, 1},
+		{"+++{{", 0, 12},
+		{"+{{{{{{{{", 0, 0},
+		{"~", 0, 255},
+		{"+~~", 0, 1},
+		{"++++++++++++$>+++++^", 1, 9},
+		{"++++++++++++$>+++++&", 1, 4},
+		{"++++++++++++$>+++++|", 1, 13},
+		{"+@+", 0, 1},
+		{"+[>+@]>+", 1, 1},
+	}
+
+	for _, tt := range tests {
+		bfm := interpreter.NewInterpreter(new(bytes.Buffer), new(bytes.Buffer), strings.NewReader(tt.code))
+		p := ebf.New()
+		if err := p.Register(bfm); err != nil {
+			t.Fatal(err)
+		}
+		if err := bfm.Run(); err != nil {
+			t.Errorf("%s: %v", tt.code, err)
+		}
+		if v := bfm.GetValueInMemory(tt.cell); v != tt.expected {
+			t.Errorf("%s: expected %d in cell %d got %d", tt.code, tt.expected, tt.cell, v)
+		}
+		p.Unregister(bfm)
+	}
+}
+
+func TestPack_CellSize(t *testing.T) {
+	tests := []struct {
+		code     string
+		bits     int
+		cell     int
+		expected int
+	}{
+		{"~", 16, 0, 65535},
+		{"+{{{{{{{{", 16, 0, 256},
+		{"+{{{{{{{{{{{{{{{{", 16, 0, 0},
+		{"-$>!", 16, 1, 65535},
+		{"~", 32, 0, 4294967295},
+		{"+{{{{{{{{{{{{{{{{", 32, 0, 65536},
+	}
+
+	for _, tt := range tests {
+		bfm := interpreter.NewInterpreter(new(bytes.Buffer), new(bytes.Buffer), strings.NewReader(tt.code), interpreter.WithCellSize(tt.bits))
+		p := ebf.New()
+		if err := p.Register(bfm); err != nil {
+			t.Fatal(err)
+		}
+		if err := bfm.Run(); err != nil {
+			t.Errorf("%s: %v", tt.code, err)
+		}
+		if v := bfm.GetValueInMemory(tt.cell); v != tt.expected {
+			t.Errorf("%s with %d-bit cells: expected %d got %d", tt.code, tt.bits, tt.expected, v)
+		}
+		p.Unregister(bfm)
+	}
+}
+
+func TestPack_RegisterConflict(t *testing.T) {
+	bfm := interpreter.NewInterpreter(new(bytes.Buffer), new(bytes.Buffer), strings.NewReader(""))
+	if err := bfm.AddOperator('~', func(c int, memory *interpreter.Memory) {}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = bfm.RemoveOperator('~') }()
+
+	if err := ebf.New().Register(bfm); err == nil {
+		t.Fatal("expected error for taken symbol")
+	}
+	// nothing else of the pack is left registered
+	if err := bfm.AddOperator('@', func(c int, memory *interpreter.Memory) {}); err != nil {
+		t.Errorf("expected @ to be free: %v", err)
+	}
+	_ = bfm.RemoveOperator('@')
+}
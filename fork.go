@@ -0,0 +1,160 @@
+package interpreter
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/momaee/WL/parser"
+)
+
+// Scheduler decides the order in which the threads of a Brainfork program run.
+type Scheduler int
+
+const (
+	// RoundRobin runs one instruction of every thread in turn, the output is deterministic.
+	RoundRobin Scheduler = iota
+	// Goroutines runs every thread in its own goroutine,
+	// instructions of different threads interleave in any order but never overlap.
+	Goroutines
+)
+
+// DefaultMaxThreads is the maximum number of threads running at once, unless set by WithMaxThreads.
+const DefaultMaxThreads = 64
+
+// ErrThreadLimit is returned by Run if a fork would exceed the maximum number of threads.
+var ErrThreadLimit = errors.New("maximum number of threads exceeded")
+
+// thread is the state of one thread of execution in Brainfork mode,
+// all threads share the cells of the memory.
+type thread struct {
+	ip     int
+	cursor int
+	calls  []int
+}
+
+// WithForks enables the 'Y' command of Brainfork, which forks the current thread:
+// the cell of the parent is set to zero and the child continues one cell to the right.
+// s decides how the threads are scheduled.
+func WithForks(s Scheduler) Option {
+	return func(b *brainFuck) {
+		b.forks = true
+		b.scheduler = s
+	}
+}
+
+// WithMaxThreads sets the maximum number of threads running at once.
+func WithMaxThreads(n int) Option {
+	return func(b *brainFuck) {
+		b.maxThreads = n
+	}
+}
+
+// load makes t the running thread of the interpreter.
+func (b *brainFuck) load(t *thread) {
+	b.ip = t.ip
+	b.memory.Cursor = t.cursor
+	b.calls = t.calls
+}
+
+// save stores the state of the running thread in t.
+func (b *brainFuck) save(t *thread) {
+	t.ip = b.ip
+	t.cursor = b.memory.Cursor
+	t.calls = b.calls
+}
+
+// fork starts a child of the running thread after the instruction at ip.
+func (b *brainFuck) fork() error {
+	if b.threads >= b.maxThreads {
+		return ErrThreadLimit
+	}
+	b.memory.Cell[b.cur()] = 0
+	child := &thread{
+		ip:     b.ip + 1,
+		cursor: b.cur() + 1,
+		calls:  append([]int(nil), b.calls...),
+	}
+	b.threads++
+	b.spawn(child)
+	return nil
+}
+
+// runThreads executes the instructions with the scheduler of the interpreter,
+// starting with a single thread in the current state.
+func (b *brainFuck) runThreads(inst []*parser.Inst) error {
+	main := &thread{}
+	b.save(main)
+	b.threads = 1
+	if b.scheduler == Goroutines {
+		return b.runGoroutines(inst, main)
+	}
+
+	queue := []*thread{main}
+	b.spawn = func(t *thread) {
+		queue = append(queue, t)
+	}
+	for i := 0; len(queue) > 0; {
+		if i >= len(queue) {
+			i = 0
+		}
+		// a thread forked by the last instruction starts at the end
+		t := queue[i]
+		if t.ip >= len(inst) {
+			queue = append(queue[:i], queue[i+1:]...)
+			b.threads--
+			continue
+		}
+		if err := b.stepThread(inst, t); err != nil {
+			return err
+		}
+		i++
+	}
+	return b.err
+}
+
+// runGoroutines executes every thread in its own goroutine,
+// the interpreter is locked while a thread executes an instruction.
+func (b *brainFuck) runGoroutines(inst []*parser.Inst, main *thread) error {
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		err error
+	)
+	b.spawn = func(t *thread) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				if err != nil || t.ip >= len(inst) {
+					b.threads--
+					mu.Unlock()
+					return
+				}
+				err = b.stepThread(inst, t)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	mu.Lock()
+	b.spawn(main)
+	mu.Unlock()
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+	return b.err
+}
+
+// stepThread executes the next instruction of t.
+func (b *brainFuck) stepThread(inst []*parser.Inst, t *thread) error {
+	b.load(t)
+	if err := b.step(inst[b.ip]); err != nil {
+		b.err = err
+		return err
+	}
+	b.ip++
+	b.save(t)
+	return nil
+}
@@ -0,0 +1,289 @@
+// Package format pretty-prints and minifies Brainfuck source.
+// Both Source and Minify keep the sequence of commands of the input,
+// so the parsed instructions of the result behave exactly like the original.
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/momaee/WL/lexer"
+	"github.com/momaee/WL/token"
+)
+
+// Options configures the pretty printer.
+// Indent is written once per loop nesting level.
+// Width is the maximum line length, lines are not wrapped if Width <= 0.
+type Options struct {
+	Indent string
+	Width  int
+}
+
+// DefaultOptions is used by cmd/bf fmt.
+var DefaultOptions = Options{Indent: "  ", Width: 80}
+
+// kinds of nodes
+const (
+	command = iota
+	comment
+	loop
+	blank
+)
+
+// node is one element of the source tree.
+// loops keep their body in children
+// trailing is set for comments which start on the line of the previous command
+type node struct {
+	kind     int
+	text     string
+	trailing bool
+	children []*node
+}
+
+// Source formats the code read from src.
+// every loop which does not fit on one line is opened and closed on its own line
+// and its body is indented. comments and blank lines between blocks are preserved.
+func Source(src io.Reader, opts Options) ([]byte, error) {
+	root, err := tree(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &printer{opts: opts}
+	p.nodes(root)
+	p.flush()
+	return p.buf.Bytes(), nil
+}
+
+// Minify returns only the commands of src,
+// with adjacent cancelling operations like "+-" and "<>" removed.
+// this is safe outside of the tape as well, where '+' and '-' do nothing, see interpreter.ErrTapeBounds.
+func Minify(src io.Reader) ([]byte, error) {
+	var out []byte
+	depth := 0
+	l := lexer.NewScanner(src)
+	for {
+		tok := l.Scan()
+		switch tok.Tok {
+		case token.EOFToken:
+			if depth != 0 {
+				return nil, fmt.Errorf("%v: missing ]", tok.Pos)
+			}
+			return out, nil
+
+		case token.IllegalToken, token.WhitespaceToken:
+			continue
+
+		case token.LeftBracketToken:
+			depth++
+
+		case token.RightBracketToken:
+			if depth == 0 {
+				return nil, fmt.Errorf("%v: unexpected ]", tok.Pos)
+			}
+			depth--
+		}
+
+		if n := len(out); n > 0 && cancels(out[n-1], tok.Value) {
+			out = out[:n-1]
+			continue
+		}
+		out = append(out, tok.Value...)
+	}
+}
+
+// cancels returns true if the command s undoes the command last.
+func cancels(last byte, s string) bool {
+	switch s {
+	case "+":
+		return last == '-'
+	case "-":
+		return last == '+'
+	case "<":
+		return last == '>'
+	case ">":
+		return last == '<'
+	}
+	return false
+}
+
+// tree reads the tokens of src and groups them into nodes.
+func tree(src io.Reader) ([]*node, error) {
+	var (
+		stack   [][]*node
+		nodes   []*node
+		current *node // comment which is still being read
+		newline = true
+		run     bool // last token was a command, the next equal command is folded into it
+	)
+	open := []token.Pos{}
+
+	l := lexer.NewScanner(src)
+	for {
+		tok := l.Scan()
+		folds := run && len(nodes) > 0 && nodes[len(nodes)-1].kind == command &&
+			strings.HasSuffix(nodes[len(nodes)-1].text, tok.Value)
+		run = false
+
+		switch tok.Tok {
+		case token.EOFToken:
+			if len(stack) != 0 {
+				return nil, fmt.Errorf("%v: missing ]", open[len(open)-1])
+			}
+			endComment(current)
+			return nodes, nil
+
+		case token.WhitespaceToken:
+			lines := strings.Count(tok.Value, "\n")
+			if lines == 0 {
+				if current != nil {
+					current.text += tok.Value
+				}
+				continue
+			}
+			endComment(current)
+			current = nil
+			newline = true
+			if lines > 1 && len(nodes) > 0 {
+				nodes = append(nodes, &node{kind: blank})
+			}
+
+		case token.IllegalToken:
+			if current == nil {
+				trailing := !newline && len(nodes) > 0
+				current = &node{kind: comment, trailing: trailing}
+				nodes = append(nodes, current)
+			}
+			current.text += tok.Value
+
+		default:
+			endComment(current)
+			current = nil
+			newline = false
+
+			switch tok.Tok {
+			case token.LeftBracketToken:
+				stack = append(stack, nodes)
+				open = append(open, tok.Pos)
+				nodes = nil
+
+			case token.RightBracketToken:
+				if len(stack) == 0 {
+					return nil, fmt.Errorf("%v: unexpected ]", tok.Pos)
+				}
+				body := nodes
+				nodes = append(stack[len(stack)-1], &node{kind: loop, children: body})
+				stack = stack[:len(stack)-1]
+				open = open[:len(open)-1]
+
+			default:
+				// runs are kept on one line, so the parser folds them the same way
+				run = true
+				if folds {
+					nodes[len(nodes)-1].text += tok.Value
+					continue
+				}
+				nodes = append(nodes, &node{kind: command, text: tok.Value})
+			}
+		}
+	}
+}
+
+// endComment removes the whitespace read after the last word of n.
+func endComment(n *node) {
+	if n != nil {
+		n.text = strings.TrimRightFunc(n.text, func(r rune) bool {
+			return r == ' ' || r == '\t' || r == '\r'
+		})
+	}
+}
+
+// printer writes nodes line by line.
+// line is the current line without its indentation.
+type printer struct {
+	opts  Options
+	buf   bytes.Buffer
+	line  []byte
+	depth int
+}
+
+func (p *printer) nodes(nodes []*node) {
+	for _, n := range nodes {
+		switch n.kind {
+		case command:
+			p.word(n.text)
+
+		case comment:
+			if n.trailing && len(p.line) > 0 {
+				p.line = append(p.line, ' ')
+				p.line = append(p.line, n.text...)
+				p.flush()
+				continue
+			}
+			p.flush()
+			p.writeLine(n.text)
+
+		case blank:
+			p.flush()
+			if p.buf.Len() > 0 && !bytes.HasSuffix(p.buf.Bytes(), []byte("\n\n")) {
+				p.buf.WriteByte('\n')
+			}
+
+		case loop:
+			if s, ok := inline(n); ok && (p.opts.Width <= 0 || p.indentWidth()+len(s) <= p.opts.Width) {
+				p.word(s)
+				continue
+			}
+			p.flush()
+			p.writeLine("[")
+			p.depth++
+			p.nodes(n.children)
+			p.flush()
+			p.depth--
+			p.writeLine("]")
+		}
+	}
+}
+
+// word appends s to the current line, wrapping the line first if s does not fit.
+func (p *printer) word(s string) {
+	if p.opts.Width > 0 && len(p.line) > 0 && p.indentWidth()+len(p.line)+len(s) > p.opts.Width {
+		p.flush()
+	}
+	p.line = append(p.line, s...)
+}
+
+// flush writes the current line if it is not empty.
+func (p *printer) flush() {
+	if len(p.line) == 0 {
+		return
+	}
+	p.writeLine(string(p.line))
+	p.line = p.line[:0]
+}
+
+func (p *printer) writeLine(s string) {
+	p.buf.WriteString(strings.Repeat(p.opts.Indent, p.depth))
+	p.buf.WriteString(s)
+	p.buf.WriteByte('\n')
+}
+
+func (p *printer) indentWidth() int {
+	return len(p.opts.Indent) * p.depth
+}
+
+// inline returns the loop n on a single line,
+// ok is false if the loop contains comments or other loops.
+func inline(n *node) (s string, ok bool) {
+	var b strings.Builder
+	b.WriteByte('[')
+	for _, c := range n.children {
+		if c.kind != command {
+			return "", false
+		}
+		b.WriteString(c.text)
+	}
+	b.WriteByte(']')
+	return b.String(), true
+}
@@ -0,0 +1,99 @@
+package format_test
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/momaee/WL/format"
+	"github.com/momaee/WL/lexer"
+	"github.com/momaee/WL/parser"
+)
+
+const helloWorld = `Hello World program
+++++++++ set counter
+[>++++[>++>+++>+++>+<<<<-]>+>+>->>+[<]<-]
+
+print it
+>>.>---.+++++++..+++.>>.<-.<.+++.------.--------.>>+.>++.`
+
+// instructions returns the parsed instructions of src in a comparable form.
+func instructions(t *testing.T, src string) []string {
+	t.Helper()
+	var out []string
+	for _, in := range parser.NewParser(lexer.NewScanner(strings.NewReader(src))).Parse() {
+		out = append(out, in.T.Value+strconv.Itoa(in.C))
+	}
+	return out
+}
+
+func TestSource(t *testing.T) {
+	out, err := format.Source(strings.NewReader(helloWorld), format.Options{Indent: "  ", Width: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `Hello World program
+++++++++ set counter
+[
+  >++++
+  [
+    >++>+++>+++>+
+    <<<<-
+  ]
+  >+>+>->>+[<]<-
+]
+
+print it
+>>.>---.+++++++..+++
+.>>.<-.<.+++.------.
+--------.>>+.>++.
+`
+	if string(out) != expected {
+		t.Errorf("wrong output, got\n%s", out)
+	}
+
+	if strings.Join(instructions(t, helloWorld), " ") != strings.Join(instructions(t, string(out)), " ") {
+		t.Errorf("formatting changed the instructions")
+	}
+
+	again, err := format.Source(bytes.NewReader(out), format.Options{Indent: "  ", Width: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, again) {
+		t.Errorf("formatting is not idempotent, got\n%s", again)
+	}
+}
+
+func TestSource_Unbalanced(t *testing.T) {
+	for _, src := range []string{"+[", "+]", "[[]"} {
+		if _, err := format.Source(strings.NewReader(src), format.DefaultOptions); err == nil {
+			t.Errorf("expected error for %q", src)
+		}
+	}
+}
+
+func TestMinify(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{helloWorld, "++++++++[>++++[>++>+++>+++>+<<<<-]>+>+>->>+[<]<-]>>.>---.+++++++..+++.>>.<-.<.+++.------.--------.>>+.>++."},
+		{"++ comment --- >><", "->"},
+		{"+[+-]><.", "+[]."},
+		{"<+->", ""},
+		{"<-+.", "<."},
+	}
+
+	for _, tt := range tests {
+		out, err := format.Minify(strings.NewReader(tt.src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != tt.expected {
+			t.Errorf("minify %q: expected %q got %q", tt.src, tt.expected, out)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Package generator writes Brainfuck programs which print a given text.
+// The generated programs never rely on cell wrap-around,
+// every cell moves straight to the value it needs.
+package generator
+
+import (
+	"sort"
+	"strings"
+)
+
+// Strategy selects how hard Generate tries to make the program short.
+type Strategy int
+
+const (
+	// Naive uses a single cell and adds or subtracts the difference to the next byte.
+	Naive Strategy = iota
+
+	// Multiply uses a counter cell and a multiplication loop for large differences.
+	Multiply
+
+	// Search initializes several cells with one multiplication loop,
+	// trying many layouts and keeping the shortest program.
+	Search
+)
+
+// maximum value a generated multiplication loop sets a cell to
+const maxValue = 250
+
+// String returns a program which prints s.
+func String(s string, strategy Strategy) string {
+	return Generate([]byte(s), strategy)
+}
+
+// Generate returns a program which prints data.
+func Generate(data []byte, strategy Strategy) string {
+	switch strategy {
+	case Naive:
+		return naive(data)
+	case Multiply:
+		return multiply(data)
+	default:
+		return search(data)
+	}
+}
+
+func naive(data []byte) string {
+	var b strings.Builder
+	cur := 0
+	for _, c := range data {
+		add(&b, int(c)-cur)
+		b.WriteByte('.')
+		cur = int(c)
+	}
+	return b.String()
+}
+
+// multiply keeps the value in cell 1 and uses cell 0 as loop counter.
+func multiply(data []byte) string {
+	var b strings.Builder
+	b.WriteByte('>')
+	cur := 0
+	for _, c := range data {
+		d := int(c) - cur
+		b.WriteString(change(d))
+		b.WriteByte('.')
+		cur = int(c)
+	}
+	return b.String()
+}
+
+// change returns the cheapest code which adds d to the current cell,
+// using the cell on its left as a loop counter.
+func change(d int) string {
+	sign := byte('+')
+	n := d
+	if d < 0 {
+		sign, n = '-', -d
+	}
+	best := strings.Repeat(string(sign), n)
+
+	for a := 2; a*a <= n; a++ {
+		f := (n + a/2) / a
+		r := n - a*f
+		// <+++[>+++++<-]>+
+		cost := 7 + a + f + abs(r)
+		if cost >= len(best) {
+			continue
+		}
+		var b strings.Builder
+		b.WriteByte('<')
+		b.WriteString(strings.Repeat("+", a))
+		b.WriteString("[>")
+		b.WriteString(strings.Repeat(string(sign), f))
+		b.WriteString("<-]>")
+		if sign == '-' {
+			r = -r
+		}
+		add(&b, r)
+		best = b.String()
+	}
+	return best
+}
+
+// search tries every loop count and number of cells and returns the shortest program,
+// never longer than the one of Multiply.
+func search(data []byte) string {
+	best := multiply(data)
+	if n := naive(data); len(n) < len(best) {
+		best = n
+	}
+	for n := 2; n <= 20; n++ {
+		freq := map[int]int{}
+		for _, c := range data {
+			if m := (int(c) + n/2) / n; m > 0 && m*n <= maxValue {
+				freq[m]++
+			}
+		}
+		byFreq := make([]int, 0, len(freq))
+		for m := range freq {
+			byFreq = append(byFreq, m)
+		}
+		sort.Slice(byFreq, func(i, j int) bool {
+			if freq[byFreq[i]] != freq[byFreq[j]] {
+				return freq[byFreq[i]] > freq[byFreq[j]]
+			}
+			return byFreq[i] < byFreq[j]
+		})
+
+		for k := 1; k <= len(byFreq) && k <= 8; k++ {
+			cells := append([]int(nil), byFreq[:k]...)
+			sort.Ints(cells)
+			if p := layout(data, n, cells); len(p) < len(best) {
+				best = p
+			}
+		}
+	}
+	return best
+}
+
+// layout sets cell i+1 to n*cells[i] with a single loop
+// and prints every byte from the cell which is cheapest to reach.
+func layout(data []byte, n int, cells []int) string {
+	var b strings.Builder
+	b.WriteString(strings.Repeat("+", n))
+	b.WriteByte('[')
+	for _, m := range cells {
+		b.WriteByte('>')
+		b.WriteString(strings.Repeat("+", m))
+	}
+	b.WriteString(strings.Repeat("<", len(cells)))
+	b.WriteString("-]>")
+
+	values := make([]int, len(cells))
+	for i, m := range cells {
+		values[i] = n * m
+	}
+	pos := 0
+	for _, c := range data {
+		best, cost := 0, -1
+		for i, v := range values {
+			if x := abs(i-pos) + abs(int(c)-v); cost < 0 || x < cost {
+				best, cost = i, x
+			}
+		}
+		move(&b, best-pos)
+		add(&b, int(c)-values[best])
+		b.WriteByte('.')
+		pos, values[best] = best, int(c)
+	}
+	return b.String()
+}
+
+// add writes d pluses, or -d minuses if d is negative.
+func add(b *strings.Builder, d int) {
+	if d >= 0 {
+		b.WriteString(strings.Repeat("+", d))
+	} else {
+		b.WriteString(strings.Repeat("-", -d))
+	}
+}
+
+// move writes d right moves, or -d left moves if d is negative.
+func move(b *strings.Builder, d int) {
+	if d >= 0 {
+		b.WriteString(strings.Repeat(">", d))
+	} else {
+		b.WriteString(strings.Repeat("<", -d))
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
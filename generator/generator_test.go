@@ -0,0 +1,58 @@
+package generator_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	interpreter "github.com/momaee/WL"
+	"github.com/momaee/WL/generator"
+)
+
+func run(t *testing.T, code string) string {
+	t.Helper()
+	output := new(bytes.Buffer)
+	bfm := interpreter.NewInterpreter(new(bytes.Buffer), output, strings.NewReader(code))
+	if err := bfm.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return output.String()
+}
+
+func TestGenerate(t *testing.T) {
+	texts := []string{
+		"",
+		"A",
+		"Hello World!\n",
+		"The quick brown fox jumps over the lazy dog.",
+		"\x00\x01~\xf0\x10",
+	}
+	strategies := map[string]generator.Strategy{
+		"naive":    generator.Naive,
+		"multiply": generator.Multiply,
+		"search":   generator.Search,
+	}
+
+	for name, strategy := range strategies {
+		for _, text := range texts {
+			code := generator.String(text, strategy)
+			if out := run(t, code); out != text {
+				t.Errorf("%s: program for %q printed %q", name, text, out)
+			}
+		}
+	}
+}
+
+func TestGenerate_Length(t *testing.T) {
+	text := "Hello World!\n"
+	naive := generator.String(text, generator.Naive)
+	multiply := generator.String(text, generator.Multiply)
+	search := generator.String(text, generator.Search)
+
+	if len(multiply) >= len(naive) {
+		t.Errorf("multiply (%d) is not shorter than naive (%d)", len(multiply), len(naive))
+	}
+	if len(search) >= len(multiply) {
+		t.Errorf("search (%d) is not shorter than multiply (%d)", len(search), len(multiply))
+	}
+}
@@ -0,0 +1,163 @@
+// Package golden tests Brainfuck programs against golden files in go tests.
+//
+// A directory holds the programs as *.b files, next to each one name.in is its input and name.out the expected output.
+// The input file is optional. With update, Run rewrites the .out files with the actual output,
+// the test decides when, for example with its own flag:
+//
+//	var update = flag.Bool("update", false, "rewrite the golden files")
+//
+//	func TestPrograms(t *testing.T) {
+//		golden.Run(t, "testdata", *update)
+//	}
+//
+//	go test -run TestPrograms -update
+package golden
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	interpreter "github.com/momaee/WL"
+)
+
+// DefaultMaxSteps is the step limit of every program, unless set by interpreter.WithMaxSteps.
+const DefaultMaxSteps = 10000000
+
+// Case is a program with the paths of its input and golden output files.
+// Input is empty if the program has no input file.
+type Case struct {
+	Name    string
+	Program string
+	Input   string
+	Output  string
+}
+
+// Discover returns the cases of the *.b files in dir, in the order of their names.
+func Discover(dir string) ([]Case, error) {
+	programs, err := filepath.Glob(filepath.Join(dir, "*.b"))
+	if err != nil {
+		return nil, err
+	}
+
+	cases := make([]Case, 0, len(programs))
+	for _, p := range programs {
+		base := strings.TrimSuffix(p, ".b")
+		c := Case{Name: filepath.Base(base), Program: p, Output: base + ".out"}
+		if _, err := os.Stat(base + ".in"); err == nil {
+			c.Input = base + ".in"
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		cases = append(cases, c)
+	}
+	return cases, nil
+}
+
+// Run runs every case of dir as a subtest, opts are passed to the interpreters.
+// a subtest fails if the program fails or if its output differs from the golden file,
+// if update is true the golden file is rewritten instead.
+func Run(t *testing.T, dir string, update bool, opts ...interpreter.Option) {
+	t.Helper()
+	cases, err := Discover(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cases) == 0 {
+		t.Fatalf("no programs in %s", dir)
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			got, err := c.Run(opts...)
+			if err != nil {
+				t.Fatalf("%s: %v", c.Program, err)
+			}
+
+			if update {
+				if err := os.WriteFile(c.Output, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(c.Output)
+			if errors.Is(err, os.ErrNotExist) {
+				t.Fatalf("%s is missing, run with update to create it", c.Output)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := Diff(string(want), string(got)); d != "" {
+				t.Errorf("output of %s differs from %s:\n%s", c.Program, c.Output, d)
+			}
+		})
+	}
+}
+
+// Run runs the program of the case with its input and returns the output.
+// reading past the end of the input is not an error.
+func (c Case) Run(opts ...interpreter.Option) ([]byte, error) {
+	code, err := os.ReadFile(c.Program)
+	if err != nil {
+		return nil, err
+	}
+	var input []byte
+	if c.Input != "" {
+		if input, err = os.ReadFile(c.Input); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	opts = append([]interpreter.Option{interpreter.WithMaxSteps(DefaultMaxSteps)}, opts...)
+	err = interpreter.NewInterpreter(bytes.NewReader(input), &out, bytes.NewReader(code), opts...).Run()
+	if err == io.EOF {
+		err = nil
+	}
+	return out.Bytes(), err
+}
+
+// Diff returns the lines of want and got between their common beginning and end,
+// prefixed by - and + respectively, or "" if they are equal.
+// the lines are quoted so that whitespace and control characters are visible.
+func Diff(want, got string) string {
+	if want == got {
+		return ""
+	}
+	w := lines(want)
+	g := lines(got)
+
+	start := 0
+	for start < len(w) && start < len(g) && w[start] == g[start] {
+		start++
+	}
+	end := 0
+	for end < len(w)-start && end < len(g)-start && w[len(w)-1-end] == g[len(g)-1-end] {
+		end++
+	}
+
+	var b strings.Builder
+	for i := start; i < len(w)-end; i++ {
+		fmt.Fprintf(&b, "%d: - %q\n", i+1, w[i])
+	}
+	for i := start; i < len(g)-end; i++ {
+		fmt.Fprintf(&b, "%d: + %q\n", i+1, g[i])
+	}
+	return b.String()
+}
+
+// lines splits s after each newline, without an empty last line.
+func lines(s string) []string {
+	l := strings.SplitAfter(s, "\n")
+	if l[len(l)-1] == "" {
+		l = l[:len(l)-1]
+	}
+	return l
+}
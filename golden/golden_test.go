@@ -0,0 +1,86 @@
+package golden_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/momaee/WL/golden"
+)
+
+// update is the flag of this test, the package does not define one
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+func TestRun(t *testing.T) {
+	golden.Run(t, "testdata", *update)
+}
+
+func TestRun_Update(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "three.b"), []byte("+++[>++++++++<-]>+++++++++++++++++++++++++."), 0644); err != nil {
+		t.Fatal(err)
+	}
+	golden.Run(t, dir, true)
+
+	out, err := os.ReadFile(filepath.Join(dir, "three.out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "1" {
+		t.Errorf("expected 1 got %q", out)
+	}
+	golden.Run(t, dir, false)
+}
+
+func TestDiscover(t *testing.T) {
+	cases, err := golden.Discover("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []golden.Case{
+		{Name: "cat", Program: filepath.Join("testdata", "cat.b"), Input: filepath.Join("testdata", "cat.in"), Output: filepath.Join("testdata", "cat.out")},
+		{Name: "hello", Program: filepath.Join("testdata", "hello.b"), Output: filepath.Join("testdata", "hello.out")},
+	}
+	if len(cases) != len(expected) {
+		t.Fatalf("expected %d cases got %d", len(expected), len(cases))
+	}
+	for i, c := range cases {
+		if c != expected[i] {
+			t.Errorf("expected %+v got %+v", expected[i], c)
+		}
+	}
+}
+
+func TestCase_Run(t *testing.T) {
+	dir := t.TempDir()
+	program := filepath.Join(dir, "loop.b")
+	if err := os.WriteFile(program, []byte("+[]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := golden.Case{Name: "loop", Program: program}
+	if _, err := c.Run(); err == nil {
+		t.Error("expected the step limit to stop an endless loop")
+	}
+
+	if _, err := (golden.Case{Program: filepath.Join(dir, "missing.b")}).Run(); !os.IsNotExist(err) {
+		t.Errorf("expected a missing file error got %v", err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		want, got string
+		expected  string
+	}{
+		{"a\nb\n", "a\nb\n", ""},
+		{"a\nb\nc\n", "a\nx\nc\n", "2: - \"b\\n\"\n2: + \"x\\n\"\n"},
+		{"a\n", "a\nb", "2: + \"b\"\n"},
+		{"a b", "a\tb", "1: - \"a b\"\n1: + \"a\\tb\"\n"},
+	}
+	for _, tt := range tests {
+		if d := golden.Diff(tt.want, tt.got); d != tt.expected {
+			t.Errorf("Diff(%q, %q) = %q, expected %q", tt.want, tt.got, d, tt.expected)
+		}
+	}
+}
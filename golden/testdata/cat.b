@@ -0,0 +1,2 @@
+copy the input to the output and clear the cell before each read so it stays zero at the end of the input
+,[.[-],]
@@ -0,0 +1,90 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/momaee/WL/parser"
+	"github.com/momaee/WL/token"
+)
+
+// default size of the grid of Paintfuck
+const (
+	DefaultGridWidth  = 10
+	DefaultGridHeight = 10
+)
+
+// WithGrid sets the grid of Paintfuck, which can be rendered after Run.
+// its width and height must be positive.
+func WithGrid(g *token.Grid) Option {
+	return func(b *brainFuck) {
+		b.grid = g
+	}
+}
+
+// WithIterations makes Paintfuck programs halt after n iterations, 0 means no limit.
+// every command counts as one iteration, also the brackets.
+func WithIterations(n int) Option {
+	return func(b *brainFuck) {
+		b.maxIterations = n
+	}
+}
+
+// checkGrid returns an error if the grid of Paintfuck has no cells.
+func (b *brainFuck) checkGrid() error {
+	if b.mode == Paintfuck && (b.grid.Width() <= 0 || b.grid.Height() <= 0) {
+		return fmt.Errorf("invalid grid size %dx%d", b.grid.Width(), b.grid.Height())
+	}
+	return nil
+}
+
+// stepGrid executes one instruction on the grid.
+func (b *brainFuck) stepGrid(in *parser.Inst) error {
+	c := in.C
+	n := c
+	if in.T.Tok == token.LeftBracketToken || in.T.Tok == token.RightBracketToken {
+		n = 1
+	}
+	if b.maxIterations > 0 {
+		left := b.maxIterations - b.iterations
+		if left <= 0 {
+			b.ip = len(b.inst)
+			return nil
+		}
+		if n > left {
+			n = left
+			c = left
+		}
+	}
+	b.iterations += n
+
+	g := b.grid
+	switch in.T.Tok {
+	case token.UpToken:
+		g.Move(0, -c)
+
+	case token.DownToken:
+		g.Move(0, c)
+
+	case token.RightToken:
+		g.Move(c, 0)
+
+	case token.LeftToken:
+		g.Move(-c, 0)
+
+	case token.FlipToken:
+		if c%2 == 1 {
+			g.Flip(g.X, g.Y)
+		}
+
+	case token.LeftBracketToken:
+		if !g.Get(g.X, g.Y) {
+			b.ip = c
+		}
+
+	case token.RightBracketToken:
+		if g.Get(g.X, g.Y) {
+			b.ip = c
+		}
+	}
+	return nil
+}
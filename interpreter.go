@@ -1,6 +1,9 @@
 package interpreter
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 
@@ -14,91 +17,379 @@ import (
 type Interpreter interface {
 	Run() error
 	AddOperator(symbol rune, operator Operator) error
+	AddContextOperator(symbol rune, operator ContextOperator) error
 	RemoveOperator(symbol rune) error
+	AddKeywordOperator(symbol string, operator Operator) error
+	AddKeywordContextOperator(symbol string, operator ContextOperator) error
+	RemoveKeyword(symbol string) error
+	Override(symbol rune, operator ContextOperator) error
+	Alias(alias rune, symbol rune) error
+	ResetOperators()
 	GetValueInMemory(position int) int
+	Flush() error
+	Steps() int
 }
 
 // brainFuck is an implementation of the Interpreter
-// it has internal parser which builds instructions from the input
+// it has internal parser which builds instructions from the code once Run is called, unless inst is set by a Program
 // result is written into w
 // memory struct keeps memory data and cursor to move between memory cells and update their values
 // err != nil if any error happen during the print/read operation
+// overrides and aliases change the built-in commands for this interpreter only
+// procs maps the number of each defined procedure to the index of its '(',
+// calls holds the instructions to return to from the running procedures
+// in Brainfork mode ip, the cursor and calls belong to the running thread, see fork.go
+// the bit modes use bits instead of memory, see bits.go, Paintfuck uses grid, see grid.go
 type brainFuck struct {
-	p      parser.RuneParser
-	w      io.Writer
-	i      io.Reader
-	buf    []byte
-	ip     int
-	err    error
-	memory Memory
+	code      io.Reader
+	inst      []*parser.Inst
+	overrides map[token.Type]ContextOperator
+	aliases   map[rune]*token.Token
+	w         io.Writer
+	i         io.Reader
+	buf       []byte
+	ip        int
+	steps     int
+	err       error
+	memory    Memory
+
+	procedures   bool
+	maxCallDepth int
+	procs        map[int]int
+	calls        []int
+
+	forks      bool
+	scheduler  Scheduler
+	maxThreads int
+	threads    int
+	spawn      func(*thread)
+
+	mode   Mode
+	bits   token.BitTape
+	bitIn  bitIO
+	bitOut bitIO
+
+	ioMode  IOMode
+	bufSize int
+	out     *bufio.Writer
+
+	grid          *token.Grid
+	maxIterations int
+	iterations    int
+
+	ctx      context.Context
+	maxSteps int
+
+	cellSize int
+	wrapTape bool
+	eof      EOFMode
+
+	tracers []Tracer
+}
+
+// DefaultMaxCallDepth is the maximum number of nested procedure calls, unless set by WithMaxCallDepth.
+const DefaultMaxCallDepth = 1024
+
+// errors returned by Run for procedure calls
+var (
+	ErrUndefinedProcedure = errors.New("undefined procedure")
+	ErrCallDepth          = errors.New("maximum call depth exceeded")
+)
+
+// Option configures an interpreter created by NewInterpreter.
+type Option func(*brainFuck)
+
+// WithContext makes Run return the error of ctx once it is cancelled.
+// ctx is checked every checkInterval steps.
+func WithContext(ctx context.Context) Option {
+	return func(b *brainFuck) {
+		b.ctx = ctx
+	}
+}
+
+// checkInterval is the number of steps between the checks of the context.
+const checkInterval = 1024
+
+// WithProcedures enables the procedures of pbrain:
+// '(' ... ')' defines a procedure numbered by the value of the current cell,
+// ':' calls the procedure whose number is in the current cell.
+func WithProcedures() Option {
+	return func(b *brainFuck) {
+		b.procedures = true
+	}
+}
+
+// WithMaxCallDepth sets the maximum number of nested procedure calls.
+func WithMaxCallDepth(n int) Option {
+	return func(b *brainFuck) {
+		b.maxCallDepth = n
+	}
 }
 
 type Memory = token.Memory
 
 type Operator = token.Operator
 
+type Context = token.Context
+
+type ContextOperator = token.ContextOperator
+
+// OperatorError is returned by Run if a ContextOperator fails.
+// Symbol and Pos identify the operator in the source, IP is the index of its instruction.
+type OperatorError struct {
+	Symbol string
+	Pos    token.Pos
+	IP     int
+	Err    error
+}
+
+func (e *OperatorError) Error() string {
+	return fmt.Sprintf("%v: operator %s: %v", e.Pos, e.Symbol, e.Err)
+}
+
+func (e *OperatorError) Unwrap() error {
+	return e.Err
+}
+
 func (b *brainFuck) execute(c int, op Operator) {
 	op(c, &b.memory)
 }
 
+// executeContext runs op with the current state of the interpreter
+// and takes over the instruction pointer it leaves in the context.
+func (b *brainFuck) executeContext(in *parser.Inst, op ContextOperator) error {
+	ctx := &Context{
+		Memory:   &b.memory,
+		Reader:   b.i,
+		Writer:   b.w,
+		IP:       b.ip,
+		Steps:    b.steps,
+		Len:      len(b.inst),
+		CellSize: b.cellSize,
+	}
+	err := op(in.C, ctx)
+	if err == nil && ctx.IP < -1 {
+		err = fmt.Errorf("invalid instruction pointer %d", ctx.IP)
+	}
+	if err != nil {
+		return &OperatorError{Symbol: in.T.Value, Pos: in.Pos, IP: b.ip, Err: err}
+	}
+	b.ip = ctx.IP
+	return nil
+}
+
 // NewInterpreter creates new Interpreter instance and initialize it's internal parser.
 // i is used to read input from io
 // w is used to write output to io
 // code is used to read instructions from io
-func NewInterpreter(i io.Reader, w io.Writer, code io.Reader) Interpreter {
-	return &brainFuck{
-		p:   parser.NewParser(lexer.NewScanner(code)),
-		w:   w,
-		i:   i,
-		buf: make([]byte, 1),
+// opts enable the extensions of the language
+func NewInterpreter(i io.Reader, w io.Writer, code io.Reader, opts ...Option) Interpreter {
+	b := &brainFuck{
+		code:         code,
+		w:            w,
+		i:            i,
+		buf:          make([]byte, 1),
+		maxCallDepth: DefaultMaxCallDepth,
+		maxThreads:   DefaultMaxThreads,
+		bufSize:      DefaultBufferSize,
+		cellSize:     DefaultCellSize,
 	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if b.mode == Paintfuck && b.grid == nil {
+		b.grid = token.NewGrid(DefaultGridWidth, DefaultGridHeight)
+	}
+	b.buffer()
+	return b
 }
 
 // Run method executes the instructions
-// err != nil if error happen during read/print operations
-// output returns in format of bytes
+// err != nil if the code can not be parsed or error happen during read/print operations
+// output returns in format of bytes, the buffered output is flushed before Run returns
 func (b *brainFuck) Run() error {
-	inst := b.p.Parse()
+	err := b.run()
+	if ferr := b.Flush(); err == nil && ferr != nil {
+		b.err = ferr
+		err = ferr
+	}
+	return err
+}
+
+func (b *brainFuck) run() error {
+	if b.inst == nil {
+		inst, err := b.parse()
+		if err != nil {
+			b.err = err
+			return err
+		}
+		b.inst = inst
+	}
+	inst := b.inst
+	if b.forks {
+		return b.runThreads(inst)
+	}
 	for b.ip < len(inst) {
-		for _, t := range token.AllTokens {
-			if inst[b.ip].T == t {
-				c := inst[b.ip].C
-				if t.HasOperator() {
-					b.execute(c, t.Operator)
-				} else {
-					switch inst[b.ip].T.Tok {
-					case token.PrintToken:
-						b.execute(c, b.write())
-
-					case token.ReadToken:
-						b.execute(c, b.read())
-
-					case token.LeftBracketToken:
-						if b.val() == 0 {
-							b.execute(c, b.jump())
-							continue
-						}
-
-					case token.RightBracketToken:
-						if b.val() != 0 {
-							b.execute(c, b.jump())
-							continue
-						}
-
-					default:
-						b.err = fmt.Errorf("unknown token %v", inst[b.ip].T.Tok)
-						return b.err
-					}
-				}
-			}
+		if err := b.step(inst[b.ip]); err != nil {
+			b.err = err
+			return err
 		}
-
 		b.ip++
 	}
 
+	b.flushBits()
 	return b.err
 }
 
+// step executes one instruction, the instruction pointer is moved to the next one by the caller.
+func (b *brainFuck) step(in *parser.Inst) error {
+	b.steps++
+	if b.maxSteps > 0 && b.steps > b.maxSteps {
+		return ErrStepLimit
+	}
+	if b.ctx != nil && b.steps%checkInterval == 0 {
+		if err := b.ctx.Err(); err != nil {
+			return err
+		}
+	}
+	if b.tracers != nil {
+		return b.traced(in)
+	}
+	return b.exec(in)
+}
+
+// exec executes one instruction in the language of the interpreter.
+func (b *brainFuck) exec(in *parser.Inst) error {
+	switch b.mode {
+	case Boolfuck, Smallfuck:
+		return b.stepBits(in)
+	case Paintfuck:
+		return b.stepGrid(in)
+	}
+	if err := b.checkTape(in); err != nil {
+		return err
+	}
+	if err := b.stepCells(in); err != nil {
+		return err
+	}
+	b.wrapCursor()
+	return nil
+}
+
+// stepCells executes one instruction on the tape of cells.
+func (b *brainFuck) stepCells(in *parser.Inst) error {
+	t := in.T
+	c := in.C
+	if op, ok := b.overrides[t.Tok]; ok {
+		return b.executeContext(in, op)
+	}
+	if t.ContextOperator != nil {
+		return b.executeContext(in, t.ContextOperator)
+	}
+	// '+' and '-' depend on the cell size of the interpreter
+	if t.HasOperator() && t.Tok != token.PlusToken && t.Tok != token.MinusToken {
+		b.execute(c, t.Operator)
+		return nil
+	}
+
+	switch t.Tok {
+	case token.PlusToken:
+		b.add(c)
+
+	case token.MinusToken:
+		b.add(-c)
+
+	case token.PrintToken:
+		b.execute(c, b.write())
+
+	case token.ReadToken:
+		b.execute(c, b.read())
+
+	case token.LeftBracketToken:
+		if b.val() == 0 {
+			b.execute(c, b.jump())
+		}
+
+	case token.RightBracketToken:
+		if b.val() != 0 {
+			b.execute(c, b.jump())
+		}
+
+	case token.ProcStartToken:
+		if b.procs == nil {
+			b.procs = map[int]int{}
+		}
+		b.procs[b.val()] = b.ip
+		b.execute(c, b.jump())
+
+	case token.ProcEndToken:
+		if len(b.calls) == 0 {
+			return fmt.Errorf("%v: return outside of a procedure", in.Pos)
+		}
+		b.ip = b.calls[len(b.calls)-1]
+		b.calls = b.calls[:len(b.calls)-1]
+
+	case token.CallToken:
+		start, ok := b.procs[b.val()]
+		if !ok {
+			return fmt.Errorf("%v: procedure %d: %w", in.Pos, b.val(), ErrUndefinedProcedure)
+		}
+		if len(b.calls) >= b.maxCallDepth {
+			return fmt.Errorf("%v: procedure %d: %w", in.Pos, b.val(), ErrCallDepth)
+		}
+		b.calls = append(b.calls, b.ip)
+		b.ip = start
+
+	case token.ForkToken:
+		if err := b.fork(); err != nil {
+			return fmt.Errorf("%v: fork: %w", in.Pos, err)
+		}
+
+	default:
+		return fmt.Errorf("unknown token %v", t.Tok)
+	}
+	return nil
+}
+
+// parse builds the instructions from the code,
+// the bit modes only recognize their own commands, otherwise it recognizes
+// the aliases of this interpreter and the enabled extensions in addition to the registered symbols.
+func (b *brainFuck) parse() ([]*parser.Inst, error) {
+	if err := b.checkCellSize(); err != nil {
+		return nil, err
+	}
+	if err := b.checkGrid(); err != nil {
+		return nil, err
+	}
+	var opts []lexer.Option
+	if symbols := b.mode.symbols(); symbols != nil {
+		m := map[string]*token.Token{}
+		for r, tok := range symbols {
+			m[string(r)] = tok
+		}
+		opts = append(opts, lexer.WithSymbols(m))
+	} else if len(b.aliases) > 0 || b.procedures || b.forks {
+		symbols := token.Symbols()
+		if b.procedures {
+			for r, tok := range token.ProcedureTokens {
+				symbols[string(r)] = tok
+			}
+		}
+		if b.forks {
+			for r, tok := range token.ForkTokens {
+				symbols[string(r)] = tok
+			}
+		}
+		for r, tok := range b.aliases {
+			symbols[string(r)] = tok
+		}
+		opts = append(opts, lexer.WithSymbols(symbols))
+	}
+	p := parser.NewParser(lexer.NewScanner(b.code, opts...))
+	inst := p.Parse()
+	return inst, p.Err()
+}
+
 // cur method returns the position of current cursor in the memory
 func (b *brainFuck) cur() int {
 	return b.memory.Cursor
@@ -111,27 +402,31 @@ func (b *brainFuck) jump() Operator {
 	}
 }
 
-// read reads input from io
+// read reads input from io, see IOMode
 // if any error happen during the Read operation err property will be set.
 func (b *brainFuck) read() Operator {
 	return func(times int, memory *Memory) {
 		for i := 0; i < times; i++ {
-			if _, err := b.i.Read(b.buf); err != nil {
+			v, err := b.input()
+			if err == io.EOF && b.eof != EOFError {
+				b.readEOF(memory)
+				continue
+			}
+			if err != nil {
 				b.err = err
 				return
 			}
-			memory.Cell[memory.Cursor] = int(b.buf[0])
+			memory.Cell[memory.Cursor] = b.wrap(v)
 		}
 	}
 }
 
-// write method prints the value in current Cell of the memory
+// write method prints the value in current Cell of the memory, see IOMode
 // if any error happen during the Write operation err property will be set.
 func (b *brainFuck) write() Operator {
 	return func(times int, memory *Memory) {
-		b.buf[0] = byte(memory.Cell[memory.Cursor])
 		for i := 0; i < times; i++ {
-			if _, err := b.w.Write(b.buf); err != nil {
+			if err := b.output(memory.Cell[memory.Cursor]); err != nil {
 				b.err = err
 				return
 			}
@@ -149,13 +444,103 @@ func (b *brainFuck) AddOperator(symbol rune, operator Operator) error {
 	return token.AddOperator(symbol, operator)
 }
 
+// AddContextOperator adds new ContextOperator to the lexer
+func (b *brainFuck) AddContextOperator(symbol rune, operator ContextOperator) error {
+	return token.AddContextOperator(symbol, operator)
+}
+
 // RemoveOperator removes Operator from the lexer
 func (b *brainFuck) RemoveOperator(symbol rune) error {
 	return token.RemoveOperator(symbol)
 }
 
+// AddKeywordOperator adds new Operator with a symbol of one or more runes to the lexer
+func (b *brainFuck) AddKeywordOperator(symbol string, operator Operator) error {
+	return token.AddKeywordOperator(symbol, operator)
+}
+
+// AddKeywordContextOperator adds new ContextOperator with a symbol of one or more runes to the lexer
+func (b *brainFuck) AddKeywordContextOperator(symbol string, operator ContextOperator) error {
+	return token.AddKeywordContextOperator(symbol, operator)
+}
+
+// RemoveKeyword removes the operator with the given symbol from the lexer
+func (b *brainFuck) RemoveKeyword(symbol string) error {
+	return token.RemoveKeyword(symbol)
+}
+
+// Override replaces the behavior of the built-in command symbol, for this interpreter only.
+// it applies to the aliases of the command as well.
+func (b *brainFuck) Override(symbol rune, operator ContextOperator) error {
+	tok := b.lookup(symbol)
+	if tok == nil || !tok.IsBuiltin() {
+		return fmt.Errorf("symbol %q is not a built-in command", symbol)
+	}
+	if b.overrides == nil {
+		b.overrides = map[token.Type]ContextOperator{}
+	}
+	b.overrides[tok.Tok] = operator
+	return nil
+}
+
+// Alias makes alias another symbol for the command symbol, for this interpreter only.
+func (b *brainFuck) Alias(alias rune, symbol rune) error {
+	if b.lookup(alias) != nil {
+		return fmt.Errorf("symbol %q already exists", alias)
+	}
+	tok := b.lookup(symbol)
+	if tok == nil {
+		return fmt.Errorf("symbol %q does not exist", symbol)
+	}
+	if b.aliases == nil {
+		b.aliases = map[rune]*token.Token{}
+	}
+	b.aliases[alias] = tok
+	return nil
+}
+
+// ResetOperators removes all overrides and aliases of this interpreter.
+func (b *brainFuck) ResetOperators() {
+	b.overrides = nil
+	b.aliases = nil
+}
+
+// lookup returns the token of symbol, nil if it is unknown.
+// the built-in commands are found even if they have been removed from the registry.
+func (b *brainFuck) lookup(symbol rune) *token.Token {
+	if tok, ok := b.aliases[symbol]; ok {
+		return tok
+	}
+	if tok, ok := token.AllTokens[symbol]; ok {
+		return tok
+	}
+	return token.BuiltinSymbol(symbol)
+}
+
+// Steps returns the number of instructions executed so far.
+func (b *brainFuck) Steps() int {
+	return b.steps
+}
+
+// GetValueInMemory returns the value of the cell at position, the bit at position in the bit modes.
+// in Paintfuck mode the bits of the grid are numbered row by row.
 func (b *brainFuck) GetValueInMemory(position int) int {
-	if position < 0 || position > len(b.memory.Cell) {
+	if b.mode == Paintfuck {
+		if position < 0 || position >= b.grid.Width()*b.grid.Height() {
+			return 0
+		}
+		if b.grid.Get(position%b.grid.Width(), position/b.grid.Width()) {
+			return 1
+		}
+		return 0
+	}
+	if b.mode != Brainfuck {
+		if b.bits.Get(position) {
+			return 1
+		}
+		return 0
+	}
+	if position < 0 || position >= len(b.memory.Cell) {
 		return 0
 	}
 	return b.memory.Cell[position]
@@ -3,11 +3,15 @@ package interpreter_test
 import (
 	"bytes"
 	"fmt"
+	"image/png"
+	"io"
 	"math"
+	"os"
 	"strings"
 	"testing"
 
 	interpreter "github.com/momaee/WL"
+	"github.com/momaee/WL/token"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -157,6 +161,9 @@ func TestRemoveOperatorNoError(t *testing.T) {
 	})
 
 	t.Run("2", func(t *testing.T) {
+		plus := token.AllTokens['+']
+		defer func() { token.AllTokens['+'] = plus }()
+
 		code := strings.NewReader("++++")
 
 		i := new(bytes.Buffer)
@@ -177,3 +184,686 @@ func TestRemoveOperatorNoError(t *testing.T) {
 
 	})
 }
+
+func TestAddContextOperator(t *testing.T) {
+	t.Run("io", func(t *testing.T) {
+		// '?' echoes c bytes of input in upper case
+		output := new(bytes.Buffer)
+		bfm := interpreter.NewInterpreter(strings.NewReader("abc"), output, strings.NewReader("??"))
+
+		err := bfm.AddContextOperator('?', func(c int, ctx *interpreter.Context) error {
+			buf := make([]byte, c)
+			if _, err := ctx.Reader.Read(buf); err != nil {
+				return err
+			}
+			_, err := ctx.Writer.Write(bytes.ToUpper(buf))
+			return err
+		})
+		assert.NoError(t, err)
+		defer func() { _ = bfm.RemoveOperator('?') }()
+
+		err = bfm.Run()
+		assert.NoError(t, err)
+		assert.Equal(t, "AB", output.String())
+	})
+
+	t.Run("jump", func(t *testing.T) {
+		// '~' skips the next instruction
+		bfm := interpreter.NewInterpreter(new(bytes.Buffer), new(bytes.Buffer), strings.NewReader("+~>++"))
+
+		steps := 0
+		err := bfm.AddContextOperator('~', func(c int, ctx *interpreter.Context) error {
+			steps = ctx.Steps
+			ctx.IP++
+			return nil
+		})
+		assert.NoError(t, err)
+		defer func() { _ = bfm.RemoveOperator('~') }()
+
+		err = bfm.Run()
+		assert.NoError(t, err)
+		assert.Equal(t, 3, bfm.GetValueInMemory(0))
+		assert.Equal(t, 2, steps)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		bfm := interpreter.NewInterpreter(new(bytes.Buffer), new(bytes.Buffer), strings.NewReader("+\n +!+"))
+
+		failure := fmt.Errorf("failure")
+		err := bfm.AddContextOperator('!', func(c int, ctx *interpreter.Context) error {
+			return failure
+		})
+		assert.NoError(t, err)
+		defer func() { _ = bfm.RemoveOperator('!') }()
+
+		err = bfm.Run()
+		assert.ErrorIs(t, err, failure)
+		assert.EqualError(t, err, "2:3: operator !: failure")
+
+		var opErr *interpreter.OperatorError
+		if assert.ErrorAs(t, err, &opErr) {
+			assert.Equal(t, "!", opErr.Symbol)
+			assert.Equal(t, 2, opErr.IP)
+		}
+		assert.Equal(t, 2, bfm.GetValueInMemory(0))
+	})
+}
+
+func TestAddKeywordOperator(t *testing.T) {
+	code := strings.NewReader("+++>++ swap ** ") // swap cells, then square the first one
+
+	bfm := interpreter.NewInterpreter(new(bytes.Buffer), new(bytes.Buffer), code)
+
+	err := bfm.AddKeywordOperator("swap", func(c int, memory *interpreter.Memory) {
+		cur := memory.Cursor
+		memory.Cell[cur-1], memory.Cell[cur] = memory.Cell[cur], memory.Cell[cur-1]
+	})
+	assert.NoError(t, err)
+	defer func() { _ = bfm.RemoveKeyword("swap") }()
+
+	err = bfm.AddKeywordOperator("**", func(c int, memory *interpreter.Memory) {
+		memory.Cell[memory.Cursor-1] *= memory.Cell[memory.Cursor-1]
+	})
+	assert.NoError(t, err)
+	defer func() { _ = bfm.RemoveKeyword("**") }()
+
+	err = bfm.AddKeywordOperator("swap", func(c int, memory *interpreter.Memory) {})
+	assert.Error(t, err)
+
+	err = bfm.Run()
+	assert.NoError(t, err)
+	assert.Equal(t, 4, bfm.GetValueInMemory(0))
+	assert.Equal(t, 3, bfm.GetValueInMemory(1))
+}
+
+func TestOverride(t *testing.T) {
+	decimal := func(c int, ctx *interpreter.Context) error {
+		for i := 0; i < c; i++ {
+			if _, err := fmt.Fprintf(ctx.Writer, "%d ", ctx.Memory.Cell[ctx.Memory.Cursor]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	output := new(bytes.Buffer)
+	bfm := interpreter.NewInterpreter(new(bytes.Buffer), output, strings.NewReader("++++++++[>+++++++++<-]>."))
+	assert.NoError(t, bfm.Override('.', decimal))
+	assert.Error(t, bfm.Override('%', decimal))
+	assert.NoError(t, bfm.Run())
+	assert.Equal(t, "72 ", output.String())
+
+	// other interpreters are not affected
+	output = new(bytes.Buffer)
+	other := interpreter.NewInterpreter(new(bytes.Buffer), output, strings.NewReader("++++++++[>+++++++++<-]>."))
+	assert.NoError(t, other.Run())
+	assert.Equal(t, "H", output.String())
+}
+
+func TestAlias(t *testing.T) {
+	t.Run("alias", func(t *testing.T) {
+		output := new(bytes.Buffer)
+		bfm := interpreter.NewInterpreter(new(bytes.Buffer), output, strings.NewReader("ppp hope:"))
+
+		assert.NoError(t, bfm.Alias('p', '+'))
+		assert.NoError(t, bfm.Alias(':', '.'))
+		assert.Error(t, bfm.Alias('+', '-'))
+		assert.Error(t, bfm.Alias('q', '%'))
+		assert.NoError(t, bfm.Override(':', func(c int, ctx *interpreter.Context) error {
+			_, err := fmt.Fprint(ctx.Writer, ctx.Memory.Cell[ctx.Memory.Cursor])
+			return err
+		}))
+
+		assert.NoError(t, bfm.Run())
+		assert.Equal(t, 4, bfm.GetValueInMemory(0))
+		assert.Equal(t, "4", output.String())
+	})
+
+	t.Run("removed operator", func(t *testing.T) {
+		plus := token.AllTokens['+']
+		defer func() { token.AllTokens['+'] = plus }()
+
+		// '+' is a comment now, the alias and the override still reach the built-in command
+		bfm := interpreter.NewInterpreter(new(bytes.Buffer), new(bytes.Buffer), strings.NewReader("pp+"))
+		assert.NoError(t, bfm.RemoveOperator('+'))
+		assert.NoError(t, bfm.Alias('p', '+'))
+		assert.NoError(t, bfm.Override('+', func(c int, ctx *interpreter.Context) error {
+			ctx.Memory.Cell[ctx.Memory.Cursor] += 10 * c
+			return nil
+		}))
+
+		assert.NoError(t, bfm.Run())
+		assert.Equal(t, 20, bfm.GetValueInMemory(0))
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		output := new(bytes.Buffer)
+		bfm := interpreter.NewInterpreter(new(bytes.Buffer), output, strings.NewReader("+++p."))
+
+		assert.NoError(t, bfm.Alias('p', '+'))
+		assert.NoError(t, bfm.Override('.', func(c int, ctx *interpreter.Context) error {
+			return fmt.Errorf("overridden")
+		}))
+		bfm.ResetOperators()
+
+		assert.NoError(t, bfm.Run())
+		assert.Equal(t, 3, bfm.GetValueInMemory(0))
+		assert.Equal(t, "\x03", output.String())
+	})
+}
+
+func TestProcedures(t *testing.T) {
+	// procedure 0 prints the next cell and increments it, procedure 1 calls procedure 0
+	setup := "(>.+<)+(-:+)>" + strings.Repeat("+", 65) + "<"
+
+	tests := []struct {
+		name     string
+		code     string
+		opts     []interpreter.Option
+		expected string
+		err      error
+	}{
+		{name: "call", code: "(>.+<)>" + strings.Repeat("+", 65) + "<:::", expected: "ABC"},
+		{name: "nested", code: setup + "::", expected: "AB"},
+		{name: "undefined", code: setup + "+:", err: interpreter.ErrUndefinedProcedure},
+		{name: "recursion", code: "+(:):", err: interpreter.ErrCallDepth},
+		{name: "max depth", code: setup + ":", opts: []interpreter.Option{interpreter.WithMaxCallDepth(1)}, err: interpreter.ErrCallDepth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := new(bytes.Buffer)
+			opts := append([]interpreter.Option{interpreter.WithProcedures()}, tt.opts...)
+			bfm := interpreter.NewInterpreter(new(bytes.Buffer), output, strings.NewReader(tt.code), opts...)
+
+			err := bfm.Run()
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, output.String())
+		})
+	}
+
+	t.Run("error position", func(t *testing.T) {
+		bfm := interpreter.NewInterpreter(new(bytes.Buffer), new(bytes.Buffer), strings.NewReader("+++\n :"), interpreter.WithProcedures())
+		assert.EqualError(t, bfm.Run(), "2:2: procedure 3: undefined procedure")
+	})
+
+	t.Run("unmatched", func(t *testing.T) {
+		bfm := interpreter.NewInterpreter(new(bytes.Buffer), new(bytes.Buffer), strings.NewReader("(+]"), interpreter.WithProcedures())
+		assert.EqualError(t, bfm.Run(), "1:3: ] does not match ( at 1:1")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		bfm := interpreter.NewInterpreter(new(bytes.Buffer), new(bytes.Buffer), strings.NewReader("+(+):"))
+		assert.NoError(t, bfm.Run())
+		assert.Equal(t, 2, bfm.GetValueInMemory(0))
+	})
+}
+
+func TestForks(t *testing.T) {
+	// the parent prints the cell after the fork, the child the one after that
+	code := ">" + strings.Repeat("+", 65) + ">" + strings.Repeat("+", 66) + "<<Y>."
+
+	t.Run("round robin", func(t *testing.T) {
+		output := new(bytes.Buffer)
+		bfm := interpreter.NewInterpreter(new(bytes.Buffer), output, strings.NewReader(code), interpreter.WithForks(interpreter.RoundRobin))
+
+		assert.NoError(t, bfm.Run())
+		assert.Equal(t, "BA", output.String())
+	})
+
+	t.Run("goroutines", func(t *testing.T) {
+		output := new(bytes.Buffer)
+		bfm := interpreter.NewInterpreter(new(bytes.Buffer), output, strings.NewReader(code), interpreter.WithForks(interpreter.Goroutines))
+
+		assert.NoError(t, bfm.Run())
+		assert.Len(t, output.String(), 2)
+		assert.Contains(t, output.String(), "A")
+		assert.Contains(t, output.String(), "B")
+	})
+
+	t.Run("thread limit", func(t *testing.T) {
+		for _, s := range []interpreter.Scheduler{interpreter.RoundRobin, interpreter.Goroutines} {
+			bfm := interpreter.NewInterpreter(new(bytes.Buffer), new(bytes.Buffer), strings.NewReader("+[Y+]"),
+				interpreter.WithForks(s), interpreter.WithMaxThreads(4))
+			err := bfm.Run()
+			assert.ErrorIs(t, err, interpreter.ErrThreadLimit)
+			assert.EqualError(t, err, "1:3: fork: maximum number of threads exceeded")
+		}
+	})
+
+	t.Run("fork at the end", func(t *testing.T) {
+		for _, s := range []interpreter.Scheduler{interpreter.RoundRobin, interpreter.Goroutines} {
+			bfm := interpreter.NewInterpreter(new(bytes.Buffer), new(bytes.Buffer), strings.NewReader("+Y"), interpreter.WithForks(s))
+			assert.NoError(t, bfm.Run())
+			assert.Equal(t, 0, bfm.GetValueInMemory(0))
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		bfm := interpreter.NewInterpreter(new(bytes.Buffer), new(bytes.Buffer), strings.NewReader("+Y+"))
+		assert.NoError(t, bfm.Run())
+		assert.Equal(t, 2, bfm.GetValueInMemory(0))
+	})
+}
+
+func TestModes(t *testing.T) {
+	t.Run("boolfuck", func(t *testing.T) {
+		tests := []struct {
+			code     string
+			input    string
+			expected string
+		}{
+			{code: ";+;;;;;;;", expected: "\xfe"},
+			{code: "+;;", expected: "\x03"},
+			{code: ",;,;", input: "\x02", expected: "\x02"},
+			{code: strings.Repeat(",>", 16) + strings.Repeat("<", 8) + strings.Repeat(";>", 8), input: "AB", expected: "B"},
+		}
+		for _, tt := range tests {
+			output := new(bytes.Buffer)
+			bfm := interpreter.NewInterpreter(strings.NewReader(tt.input), output, strings.NewReader(tt.code), interpreter.WithMode(interpreter.Boolfuck))
+			assert.NoError(t, bfm.Run())
+			assert.Equal(t, tt.expected, output.String(), tt.code)
+		}
+	})
+
+	t.Run("smallfuck", func(t *testing.T) {
+		bfm := interpreter.NewInterpreter(new(bytes.Buffer), new(bytes.Buffer), strings.NewReader("*>*>*<[*<]*"), interpreter.WithMode(interpreter.Smallfuck))
+		assert.NoError(t, bfm.Run())
+		assert.Equal(t, 0, bfm.GetValueInMemory(0))
+		assert.Equal(t, 0, bfm.GetValueInMemory(1))
+		assert.Equal(t, 1, bfm.GetValueInMemory(2))
+		// the program ends when the pointer leaves the tape, before the last '*'
+		assert.Equal(t, 0, bfm.GetValueInMemory(-1))
+	})
+}
+
+func TestPaintfuck(t *testing.T) {
+	tests := []struct {
+		code       string
+		width      int
+		height     int
+		iterations int
+		expected   string
+	}{
+		{code: "*e*s*", width: 3, height: 2, expected: "110\n010"},
+		{code: "w*n*nn", width: 3, height: 2, expected: "001\n001"},
+		{code: "*e*e*", width: 3, height: 1, iterations: 3, expected: "110"},
+		{code: "*[e*]", width: 4, height: 1, iterations: 5, expected: "1100"},
+		{code: "eee*", width: 4, height: 1, iterations: 2, expected: "0000"},
+	}
+
+	for _, tt := range tests {
+		grid := token.NewGrid(tt.width, tt.height)
+		bfm := interpreter.NewInterpreter(new(bytes.Buffer), new(bytes.Buffer), strings.NewReader(tt.code),
+			interpreter.WithMode(interpreter.Paintfuck), interpreter.WithGrid(grid), interpreter.WithIterations(tt.iterations))
+
+		assert.NoError(t, bfm.Run())
+		assert.Equal(t, tt.expected, grid.String(), tt.code)
+	}
+
+	t.Run("default grid", func(t *testing.T) {
+		bfm := interpreter.NewInterpreter(new(bytes.Buffer), new(bytes.Buffer), strings.NewReader("sw*"), interpreter.WithMode(interpreter.Paintfuck))
+		assert.NoError(t, bfm.Run())
+		assert.Equal(t, 1, bfm.GetValueInMemory(2*interpreter.DefaultGridWidth-1))
+	})
+
+	t.Run("outside of the grid", func(t *testing.T) {
+		bfm := interpreter.NewInterpreter(new(bytes.Buffer), new(bytes.Buffer), strings.NewReader("*"), interpreter.WithMode(interpreter.Paintfuck))
+		assert.NoError(t, bfm.Run())
+		assert.Equal(t, 1, bfm.GetValueInMemory(0))
+		assert.Equal(t, 0, bfm.GetValueInMemory(interpreter.DefaultGridWidth*interpreter.DefaultGridHeight))
+		assert.Equal(t, 0, bfm.GetValueInMemory(1000))
+		assert.Equal(t, 0, bfm.GetValueInMemory(-1))
+	})
+
+	t.Run("empty grid", func(t *testing.T) {
+		for _, grid := range []*token.Grid{token.NewGrid(0, 0), token.NewGrid(3, 0), token.NewGrid(-1, 2)} {
+			bfm := interpreter.NewInterpreter(new(bytes.Buffer), new(bytes.Buffer), strings.NewReader("e*"),
+				interpreter.WithMode(interpreter.Paintfuck), interpreter.WithGrid(grid))
+			assert.EqualError(t, bfm.Run(), fmt.Sprintf("invalid grid size %dx%d", grid.Width(), grid.Height()))
+		}
+	})
+
+	t.Run("png", func(t *testing.T) {
+		grid := token.NewGrid(2, 1)
+		bfm := interpreter.NewInterpreter(new(bytes.Buffer), new(bytes.Buffer), strings.NewReader("e*"),
+			interpreter.WithMode(interpreter.Paintfuck), interpreter.WithGrid(grid))
+		assert.NoError(t, bfm.Run())
+
+		buf := new(bytes.Buffer)
+		assert.NoError(t, png.Encode(buf, grid))
+		img, err := png.Decode(buf)
+		assert.NoError(t, err)
+		r, _, _, _ := img.At(0, 0).RGBA()
+		assert.Equal(t, uint32(0xffff), r)
+		r, _, _, _ = img.At(1, 0).RGBA()
+		assert.Equal(t, uint32(0), r)
+	})
+}
+
+func TestIOModes(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     interpreter.IOMode
+		bits     int
+		code     string
+		input    string
+		expected string
+		err      bool
+	}{
+		{name: "byte", mode: interpreter.ByteIO, code: ",+.", input: "a", expected: "b"},
+		{name: "rune", mode: interpreter.RuneIO, bits: 32, code: ",.,.,.>+++.", input: "é€😀", expected: "é€😀\x03"},
+		{name: "rune wider than the cell", mode: interpreter.RuneIO, code: ",.", input: "€", expected: "\u00ac"},
+		{name: "rune wider than 16 bits", mode: interpreter.RuneIO, bits: 16, code: ",.", input: "😀", expected: "\uf600"},
+		{name: "truncated rune", mode: interpreter.RuneIO, code: ",", input: "\xe2\x82", err: true},
+		{name: "number", mode: interpreter.NumberIO, code: ",>,[-<+>]<.", input: " 12\n\t30 ", expected: "42\n"},
+		{name: "number at end of input", mode: interpreter.NumberIO, code: ",.", input: "7", expected: "7\n"},
+		{name: "invalid number", mode: interpreter.NumberIO, code: ",", input: "x", err: true},
+		{name: "number wider than the cell", mode: interpreter.NumberIO, code: ",.", input: "300", expected: "44\n"},
+		{name: "negative number", mode: interpreter.NumberIO, code: ",.", input: "-1", expected: "255\n"},
+		{name: "number wider than 16 bits", mode: interpreter.NumberIO, bits: 16, code: ",+.", input: "70000", expected: "4465\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := new(bytes.Buffer)
+			opts := []interpreter.Option{interpreter.WithIO(tt.mode)}
+			if tt.bits != 0 {
+				opts = append(opts, interpreter.WithCellSize(tt.bits))
+			}
+			bfm := interpreter.NewInterpreter(strings.NewReader(tt.input), output, strings.NewReader(tt.code), opts...)
+			err := bfm.Run()
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, output.String())
+		})
+	}
+}
+
+// promptReader records the output written before the input is read.
+type promptReader struct {
+	output *bytes.Buffer
+	prompt string
+	input  string
+}
+
+func (r *promptReader) Read(p []byte) (int, error) {
+	r.prompt = r.output.String()
+	n := copy(p, r.input)
+	r.input = r.input[n:]
+	return n, nil
+}
+
+func TestBufferedIO(t *testing.T) {
+	t.Run("flush before input", func(t *testing.T) {
+		output := new(bytes.Buffer)
+		input := &promptReader{output: output, input: "x"}
+		bfm := interpreter.NewInterpreter(input, output, strings.NewReader(strings.Repeat("+", 63)+".,."))
+
+		assert.NoError(t, bfm.Run())
+		assert.Equal(t, "?", input.prompt)
+		assert.Equal(t, "?x", output.String())
+	})
+
+	t.Run("buffer size", func(t *testing.T) {
+		for _, size := range []int{0, 16} {
+			output := new(bytes.Buffer)
+			written := ""
+			bfm := interpreter.NewInterpreter(new(bytes.Buffer), output, strings.NewReader("~"), interpreter.WithBufferSize(size))
+			err := bfm.AddContextOperator('~', func(c int, ctx *interpreter.Context) error {
+				_, err := ctx.Writer.Write([]byte("x"))
+				written = output.String()
+				return err
+			})
+			assert.NoError(t, err)
+
+			assert.NoError(t, bfm.Run())
+			assert.NoError(t, bfm.RemoveOperator('~'))
+			assert.NoError(t, bfm.Flush())
+			assert.Equal(t, "x", output.String())
+			if size == 0 {
+				assert.Equal(t, "x", written)
+			} else {
+				assert.Equal(t, "", written)
+			}
+		}
+	})
+}
+
+// output prints 4096 bytes, input copies its input to the output up to the zero byte.
+var benchmarks = []struct {
+	name  string
+	code  string
+	input int
+}{
+	{name: "output", code: "++++++++++++++++[>++++++++++++++++[>++++++++++++++++[>.<-]<-]<-]"},
+	{name: "input", code: ",[.,]", input: 4096},
+}
+
+func BenchmarkIO(b *testing.B) {
+	for _, bm := range benchmarks {
+		for _, size := range []int{0, interpreter.DefaultBufferSize} {
+			b.Run(fmt.Sprintf("%s/buffer=%d", bm.name, size), func(b *testing.B) {
+				in, err := os.CreateTemp(b.TempDir(), "in")
+				if err != nil {
+					b.Fatal(err)
+				}
+				defer in.Close()
+				if _, err := in.Write(append(bytes.Repeat([]byte("a"), bm.input), 0)); err != nil {
+					b.Fatal(err)
+				}
+				out, err := os.CreateTemp(b.TempDir(), "out")
+				if err != nil {
+					b.Fatal(err)
+				}
+				defer out.Close()
+
+				b.SetBytes(4096)
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := in.Seek(0, 0); err != nil {
+						b.Fatal(err)
+					}
+					bfm := interpreter.NewInterpreter(in, out, strings.NewReader(bm.code), interpreter.WithBufferSize(size))
+					if err := bfm.Run(); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestReader(t *testing.T) {
+	t.Run("chain", func(t *testing.T) {
+		// the first program prints abc and a zero byte, the second one makes its input upper case
+		first := interpreter.NewReader(nil, strings.NewReader(strings.Repeat("+", 97)+".+.+.>."))
+		second := interpreter.NewReader(first, strings.NewReader(",["+strings.Repeat("-", 32)+".,]"))
+
+		out, err := io.ReadAll(second)
+		assert.NoError(t, err)
+		assert.Equal(t, "ABC", string(out))
+	})
+
+	t.Run("lazy", func(t *testing.T) {
+		// prints forever
+		r := interpreter.NewReader(nil, strings.NewReader(strings.Repeat("+", 65)+"[.]"))
+
+		out, err := io.ReadAll(io.LimitReader(r, 10))
+		assert.NoError(t, err)
+		assert.Equal(t, "AAAAAAAAAA", string(out))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		r := interpreter.NewReader(nil, strings.NewReader("+.]"))
+
+		out, err := io.ReadAll(r)
+		assert.EqualError(t, err, "1:3: unmatched ]")
+		assert.Empty(t, out)
+	})
+}
+
+func TestResumable(t *testing.T) {
+	t.Run("interactive", func(t *testing.T) {
+		// prints ? and echoes two characters
+		r := interpreter.NewResumable(strings.NewReader(strings.Repeat("+", 63) + ".,.,."))
+
+		state, err := r.Run()
+		assert.NoError(t, err)
+		assert.Equal(t, interpreter.OutputReady, state)
+		assert.Equal(t, "?", string(r.Output()))
+
+		state, err = r.Run()
+		assert.NoError(t, err)
+		assert.Equal(t, interpreter.NeedInput, state)
+		state, err = r.Run()
+		assert.NoError(t, err)
+		assert.Equal(t, interpreter.NeedInput, state)
+
+		r.Feed([]byte("ab"))
+		var out []byte
+		for state, err = r.Run(); state == interpreter.OutputReady; state, err = r.Run() {
+			out = append(out, r.Output()...)
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, interpreter.Done, state)
+		assert.Equal(t, "ab", string(out))
+	})
+
+	t.Run("folded reads", func(t *testing.T) {
+		r := interpreter.NewResumable(strings.NewReader(",,,."))
+		r.Feed([]byte("a"))
+		state, _ := r.Run()
+		assert.Equal(t, interpreter.NeedInput, state)
+		r.Feed([]byte("bc"))
+		state, _ = r.Run()
+		assert.Equal(t, interpreter.OutputReady, state)
+		assert.Equal(t, "c", string(r.Output()))
+	})
+
+	t.Run("numbers", func(t *testing.T) {
+		r := interpreter.NewResumable(strings.NewReader(",."), interpreter.WithIO(interpreter.NumberIO))
+		r.Feed([]byte("1"))
+		state, _ := r.Run()
+		assert.Equal(t, interpreter.NeedInput, state)
+		r.Feed([]byte("2"))
+		r.CloseInput()
+		state, _ = r.Run()
+		assert.Equal(t, interpreter.OutputReady, state)
+		assert.Equal(t, "12\n", string(r.Output()))
+		state, err := r.Run()
+		assert.NoError(t, err)
+		assert.Equal(t, interpreter.Done, state)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		r := interpreter.NewResumable(strings.NewReader("+["))
+		state, err := r.Run()
+		assert.Error(t, err)
+		assert.Equal(t, interpreter.Done, state)
+	})
+}
+
+func TestProgram(t *testing.T) {
+	p, err := interpreter.Compile(strings.NewReader(",[.,]"))
+	assert.NoError(t, err)
+
+	for _, input := range []string{"abc\x00", "de\x00"} {
+		output := new(bytes.Buffer)
+		bfm := p.NewInterpreter(strings.NewReader(input), output)
+		assert.NoError(t, bfm.Run())
+		assert.Equal(t, strings.TrimSuffix(input, "\x00"), output.String())
+	}
+
+	bfm := p.NewInterpreter(strings.NewReader("abc\x00"), new(bytes.Buffer), interpreter.WithMaxSteps(5))
+	assert.ErrorIs(t, bfm.Run(), interpreter.ErrStepLimit)
+	assert.Equal(t, 6, bfm.Steps())
+
+	_, err = interpreter.Compile(strings.NewReader("(]"), interpreter.WithProcedures())
+	assert.EqualError(t, err, "1:2: ] does not match ( at 1:1")
+}
+
+func TestCells(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		input    string
+		opts     []interpreter.Option
+		expected []int
+		err      error
+	}{
+		{"wrap up", strings.Repeat("+", 257), "", nil, []int{1}, nil},
+		{"wrap down", "--", "", nil, []int{254}, nil},
+		{"16 bits", strings.Repeat("+", 256) + ">-", "", []interpreter.Option{interpreter.WithCellSize(16)}, []int{256, 65535}, nil},
+		{"32 bits", "-", "", []interpreter.Option{interpreter.WithCellSize(32)}, []int{1<<32 - 1}, nil},
+		{"eof error", "+,", "", nil, []int{1}, io.EOF},
+		{"eof unchanged", "+,", "", []interpreter.Option{interpreter.WithEOF(interpreter.EOFUnchanged)}, []int{1}, nil},
+		{"eof zero", "+,,", "a", []interpreter.Option{interpreter.WithEOF(interpreter.EOFZero)}, []int{0}, nil},
+		{"eof minus one", ",", "", []interpreter.Option{interpreter.WithEOF(interpreter.EOFMinusOne)}, []int{255}, nil},
+		{"eof minus one 16 bits", ",", "", []interpreter.Option{interpreter.WithEOF(interpreter.EOFMinusOne), interpreter.WithCellSize(16)}, []int{65535}, nil},
+		{"left edge", "+<.", "", nil, []int{1}, interpreter.ErrTapeBounds},
+		{"loop left of the edge", "+<[]", "", nil, []int{1}, interpreter.ErrTapeBounds},
+		{"pass the left edge", "<>+<", "", nil, []int{1}, nil},
+		{"add left of the edge", "<+->+", "", nil, []int{1}, nil},
+		{"add right of the edge", strings.Repeat(">", token.MemorySize) + "+<+", "", nil, []int{0}, nil},
+		{"wrapping tape", "<+>>+", "", []interpreter.Option{interpreter.WithWrappingTape()}, []int{0, 1}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bfm := interpreter.NewInterpreter(strings.NewReader(tt.input), new(bytes.Buffer), strings.NewReader(tt.code), tt.opts...)
+			err := bfm.Run()
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			for i, v := range tt.expected {
+				assert.Equal(t, v, bfm.GetValueInMemory(i))
+			}
+		})
+	}
+
+	bfm := interpreter.NewInterpreter(nil, nil, strings.NewReader("+<+"), interpreter.WithWrappingTape())
+	assert.NoError(t, bfm.Run())
+	assert.Equal(t, 1, bfm.GetValueInMemory(token.MemorySize-1))
+
+	err := interpreter.NewInterpreter(nil, nil, strings.NewReader("+"), interpreter.WithCellSize(12)).Run()
+	assert.EqualError(t, err, "unsupported cell size 12")
+}
+
+type traceRecorder struct {
+	events []interpreter.TraceEvent
+}
+
+func (r *traceRecorder) Trace(e interpreter.TraceEvent) {
+	r.events = append(r.events, e)
+}
+
+func TestTracer(t *testing.T) {
+	r := &traceRecorder{}
+	bfm := interpreter.NewInterpreter(nil, new(bytes.Buffer), strings.NewReader("+[-]<+."), interpreter.WithTracer(r))
+	assert.ErrorIs(t, bfm.Run(), interpreter.ErrTapeBounds)
+
+	assert.Equal(t, []interpreter.TraceEvent{
+		{Step: 1, IP: 0, Op: "+", Count: 1, Cursor: 0, Before: 0, After: 1, Next: 1},
+		{Step: 2, IP: 1, Op: "[", Count: 3, Cursor: 0, Before: 1, After: 1, Next: 2},
+		{Step: 3, IP: 2, Op: "-", Count: 1, Cursor: 0, Before: 1, After: 0, Next: 3},
+		{Step: 4, IP: 3, Op: "]", Count: 1, Cursor: 0, Before: 0, After: 0, Next: 4},
+		{Step: 5, IP: 4, Op: "<", Count: 1, Cursor: 0, Before: 0, After: 0, Next: 5},
+		{Step: 6, IP: 5, Op: "+", Count: 1, Cursor: -1, Before: 0, After: 0, Next: 6},
+		{Step: 7, IP: 6, Op: ".", Count: 1, Cursor: -1, Before: 0, After: 0, Next: 7, Err: "1:7: cell -1: pointer outside of the tape"},
+	}, r.events)
+
+	r = &traceRecorder{}
+	bfm = interpreter.NewInterpreter(nil, nil, strings.NewReader("e*"), interpreter.WithMode(interpreter.Paintfuck), interpreter.WithTracer(r))
+	assert.NoError(t, bfm.Run())
+	assert.Equal(t, interpreter.TraceEvent{Step: 2, IP: 1, Op: "*", Count: 1, Cursor: 1, Before: 0, After: 1, Next: 2}, r.events[1])
+}
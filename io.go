@@ -0,0 +1,196 @@
+package interpreter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf8"
+)
+
+// IOMode decides how ',' and '.' convert between cells and the bytes of the I/O.
+type IOMode int
+
+const (
+	// ByteIO reads and writes one byte per cell, the default.
+	ByteIO IOMode = iota
+	// RuneIO reads and writes one UTF-8 encoded rune per cell, the cell holds the code point
+// wrapped around at the cell size, like every value read.
+	RuneIO
+	// NumberIO reads a decimal integer per cell, skipping the whitespace before it,
+	// and writes the cell as a decimal integer followed by a newline.
+	NumberIO
+)
+
+// EOFMode decides what ',' does at the end of the input.
+type EOFMode int
+
+const (
+	// EOFError leaves the cell unchanged and makes Run return io.EOF once the program has ended, the default.
+	EOFError EOFMode = iota
+	// EOFUnchanged leaves the cell unchanged.
+	EOFUnchanged
+	// EOFZero sets the cell to zero.
+	EOFZero
+	// EOFMinusOne sets the cell to -1, which is the largest value of the cell size.
+	EOFMinusOne
+)
+
+// WithEOF selects what ',' does at the end of the input.
+func WithEOF(m EOFMode) Option {
+	return func(b *brainFuck) {
+		b.eof = m
+	}
+}
+
+// DefaultBufferSize is the size of the input and output buffers, unless set by WithBufferSize.
+const DefaultBufferSize = 4096
+
+// WithBufferSize sets the size of the input and output buffers, 0 disables buffering.
+// the output is flushed before the interpreter waits for input and when Run returns.
+func WithBufferSize(n int) Option {
+	return func(b *brainFuck) {
+		b.bufSize = n
+	}
+}
+
+// WithIO selects how the interpreter reads and writes cells.
+func WithIO(m IOMode) Option {
+	return func(b *brainFuck) {
+		b.ioMode = m
+	}
+}
+
+// readEOF sets the current cell at the end of the input, see EOFMode.
+func (b *brainFuck) readEOF(memory *Memory) {
+	switch b.eof {
+	case EOFZero:
+		memory.Cell[memory.Cursor] = 0
+	case EOFMinusOne:
+		memory.Cell[memory.Cursor] = b.wrap(-1)
+	}
+}
+
+// input reads the value of one cell.
+func (b *brainFuck) input() (int, error) {
+	switch b.ioMode {
+	case RuneIO:
+		return b.inputRune()
+	case NumberIO:
+		return b.inputNumber()
+	}
+	if _, err := io.ReadFull(b.i, b.buf); err != nil {
+		return 0, err
+	}
+	return int(b.buf[0]), nil
+}
+
+// output writes the value of one cell.
+func (b *brainFuck) output(v int) error {
+	var p []byte
+	switch b.ioMode {
+	case RuneIO:
+		p = make([]byte, utf8.UTFMax)
+		p = p[:utf8.EncodeRune(p, rune(v))]
+	case NumberIO:
+		p = strconv.AppendInt(nil, int64(v), 10)
+		p = append(p, '\n')
+	default:
+		b.buf[0] = byte(v)
+		p = b.buf
+	}
+	_, err := b.w.Write(p)
+	return err
+}
+
+// inputRune reads the bytes of one UTF-8 encoded rune.
+// an invalid encoding is read as utf8.RuneError.
+func (b *brainFuck) inputRune() (int, error) {
+	var p [utf8.UTFMax]byte
+	if _, err := io.ReadFull(b.i, p[:1]); err != nil {
+		return 0, err
+	}
+	n := 1
+	switch {
+	case p[0] >= 0xf0:
+		n = 4
+	case p[0] >= 0xe0:
+		n = 3
+	case p[0] >= 0xc0:
+		n = 2
+	}
+	if n > 1 {
+		if _, err := io.ReadFull(b.i, p[1:n]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+	}
+	r, _ := utf8.DecodeRune(p[:n])
+	return int(r), nil
+}
+
+// inputNumber reads a decimal integer, the byte after it is consumed as well.
+func (b *brainFuck) inputNumber() (int, error) {
+	var digits []byte
+	for {
+		if _, err := io.ReadFull(b.i, b.buf); err != nil {
+			if err == io.EOF && len(digits) > 0 {
+				break
+			}
+			return 0, err
+		}
+		c := b.buf[0]
+		if len(digits) == 0 && (c == ' ' || c == '\t' || c == '\n' || c == '\r') {
+			continue
+		}
+		if (c >= '0' && c <= '9') || (c == '-' && len(digits) == 0) {
+			digits = append(digits, c)
+			continue
+		}
+		if len(digits) == 0 {
+			return 0, fmt.Errorf("invalid number input %q", c)
+		}
+		break
+	}
+	v, err := strconv.Atoi(string(digits))
+	if err != nil {
+		return 0, fmt.Errorf("invalid number input %q", digits)
+	}
+	return v, nil
+}
+
+// buffer wraps the input and the output of the interpreter in buffers of bufSize.
+func (b *brainFuck) buffer() {
+	if b.bufSize <= 0 {
+		return
+	}
+	b.out = bufio.NewWriterSize(b.w, b.bufSize)
+	b.w = b.out
+	b.i = &inputReader{r: bufio.NewReaderSize(b.i, b.bufSize), w: b.out}
+}
+
+// Flush writes the buffered output.
+func (b *brainFuck) Flush() error {
+	if b.out == nil {
+		return nil
+	}
+	return b.out.Flush()
+}
+
+// inputReader flushes the output before it waits for more input,
+// so the prompts of interactive programs are shown.
+type inputReader struct {
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+func (in *inputReader) Read(p []byte) (int, error) {
+	if in.r.Buffered() == 0 {
+		if err := in.w.Flush(); err != nil {
+			return 0, err
+		}
+	}
+	return in.r.Read(p)
+}
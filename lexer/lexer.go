@@ -4,7 +4,9 @@ import (
 	"bufio"
 	"bytes"
 	"io"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/momaee/WL/token"
 )
@@ -27,24 +29,65 @@ type LexScanner interface {
 }
 
 // Scanner implements a tokenizer.
+// pos is the position of the next rune, prev the position before the last read.
+// runes and keywords are the single and multi rune symbols it recognizes.
 type scanner struct {
-	r *bufio.Reader
+	r        *bufio.Reader
+	pos      token.Pos
+	prev     token.Pos
+	runes    map[rune]*token.Token
+	keywords map[string]*token.Token
+}
+
+// Option configures a Scanner.
+type Option func(*scanner)
+
+// WithSymbols makes the Scanner recognize only the given symbols
+// instead of token.AllTokens and token.Keywords.
+// the returned tokens are copies of the mapped ones, so several symbols can share a token.
+func WithSymbols(symbols map[string]*token.Token) Option {
+	return func(s *scanner) {
+		s.runes = map[rune]*token.Token{}
+		s.keywords = map[string]*token.Token{}
+		for symbol, tok := range symbols {
+			if r, size := utf8.DecodeRuneInString(symbol); size == len(symbol) {
+				s.runes[r] = tok
+			} else {
+				s.keywords[symbol] = tok
+			}
+		}
+	}
 }
 
 // NewScanner returns a new instance of Scanner.
-func NewScanner(r io.Reader) LexScanner {
-	return &scanner{
-		bufio.NewReader(r),
+func NewScanner(r io.Reader, opts ...Option) LexScanner {
+	s := &scanner{
+		r:        bufio.NewReader(r),
+		pos:      token.Pos{Line: 1, Column: 1},
+		runes:    token.AllTokens,
+		keywords: token.Keywords,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
 // Read method reads the next rune from r.
 // err != nil only if there is no more rune to read.
 func (s *scanner) read() rune {
-	ch, _, err := s.r.ReadRune()
+	ch, size, err := s.r.ReadRune()
 	if err != nil {
 		return token.EOF
 	}
+	s.prev = s.pos
+	s.pos.Offset += size
+	if ch == '\n' {
+		s.pos.Line++
+		s.pos.Column = 1
+	} else {
+		s.pos.Column++
+	}
 	return ch
 }
 
@@ -53,6 +96,7 @@ func (s *scanner) unread() error {
 	if err := s.r.UnreadRune(); err != nil {
 		return err
 	}
+	s.pos = s.prev
 	return nil
 }
 
@@ -79,7 +123,7 @@ func (s *scanner) scanLetterDigit() *token.Token {
 		ch := s.read()
 		if ch == token.EOF {
 			break
-		} else if !isLetterDigit(ch) {
+		} else if _, ok := s.runes[ch]; ok || !isLetterDigit(ch) {
 			_ = s.unread()
 			break
 		}
@@ -90,33 +134,116 @@ func (s *scanner) scanLetterDigit() *token.Token {
 }
 
 // Scan prepare and returns the next Token.
+// The returned token is a copy of the registered one and carries its source range in Pos and End.
 func (s *scanner) Scan() *token.Token {
+	pos := s.pos
+	tok := s.scan()
+	tok.Pos = pos
+	tok.End = s.pos
+	return tok
+}
+
+func (s *scanner) scan() *token.Token {
+
+	// Multi rune symbols take precedence over everything else.
+	if tok := s.scanKeyword(); tok != nil {
+		return tok
+	}
 
 	// read next rune
 	ch := s.read()
 
+	if ch == token.EOF {
+		return &token.Token{Tok: token.EOFToken}
+	}
+
+	// Operators may use any code point, including letters.
+	if tok, ok := s.runes[ch]; ok {
+		t := *tok
+		return &t
+	}
+
 	// If whitespace code point found, then consume all contiguous whitespaces.
 	if isWhitespace(ch) {
+		_ = s.unread()
 		return s.scanWhitespace()
 	}
 
 	// If letter, digit code point found, then consume all letters, digits
 	if isLetterDigit(ch) {
+		_ = s.unread()
 		return s.scanLetterDigit()
 	}
 
 	return s.next(ch)
 }
 
-func (s *scanner) next(ch rune) *token.Token {
-	// Check against individual code points next.
+// scanKeyword consumes the longest keyword found at the current position.
+// a space inside a keyword matches any run of whitespace in the input.
+// it returns nil if none of them matches.
+func (s *scanner) scanKeyword() *token.Token {
+	max := 0
+	for symbol := range s.keywords {
+		if strings.Contains(symbol, " ") {
+			max = s.r.Size()
+			break
+		}
+		if len(symbol) > max {
+			max = len(symbol)
+		}
+	}
+	if max == 0 {
+		return nil
+	}
+
+	// Peek returns less than max bytes near the end of the input.
+	ahead, _ := s.r.Peek(max)
+	var match string
+	n := 0
+	for symbol := range s.keywords {
+		if m := matchKeyword(ahead, symbol); m > n || (m == n && m > 0 && len(symbol) > len(match)) {
+			match, n = symbol, m
+		}
+	}
+	if match == "" {
+		return nil
+	}
+
+	for start := s.pos.Offset; s.pos.Offset-start < n; {
+		s.read()
+	}
+	t := *s.keywords[match]
+	return &t
+}
 
-	for symbol, tok := range token.AllTokens {
-		if ch == symbol {
-			return tok
+// matchKeyword returns the number of bytes of input matched by symbol, 0 if it does not match.
+func matchKeyword(input []byte, symbol string) int {
+	i := 0
+	for j := 0; j < len(symbol); j++ {
+		if symbol[j] != ' ' {
+			if i >= len(input) || input[i] != symbol[j] {
+				return 0
+			}
+			i++
+			continue
+		}
+		start := i
+		for i < len(input) {
+			r, size := utf8.DecodeRune(input[i:])
+			if !isWhitespace(r) {
+				break
+			}
+			i += size
+		}
+		if i == start {
+			return 0
 		}
 	}
+	return i
+}
 
+// next returns an illegal token for ch, which is not a known symbol.
+func (s *scanner) next(ch rune) *token.Token {
 	return &token.Token{Tok: token.IllegalToken, Value: string(ch)}
 }
 
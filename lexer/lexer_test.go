@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/momaee/WL/lexer"
+	"github.com/momaee/WL/token"
 )
 
 func TestScanner_Read(t *testing.T) {
@@ -45,3 +46,34 @@ func TestScanner_Scan(t *testing.T) {
 	}
 
 }
+
+func TestScanner_Keywords(t *testing.T) {
+	for _, symbol := range []string{"*", "**", "swap"} {
+		if err := token.AddKeywordOperator(symbol, func(c int, memory *token.Memory) {}); err != nil {
+			t.Fatal(err)
+		}
+		defer func(symbol string) { _ = token.RemoveKeyword(symbol) }(symbol)
+	}
+
+	s := lexer.NewScanner(strings.NewReader("+***swapx\nswap"))
+	expected := []struct {
+		value string
+		tok   token.Type
+		pos   string
+	}{
+		{"+", token.PlusToken, "1:1"},
+		{"**", token.UserDefinedToken, "1:2"},
+		{"*", token.UserDefinedToken, "1:4"},
+		{"swap", token.UserDefinedToken, "1:5"},
+		{"x", token.IllegalToken, "1:9"},
+		{"\n", token.WhitespaceToken, "1:10"},
+		{"swap", token.UserDefinedToken, "2:1"},
+		{"", token.EOFToken, "2:5"},
+	}
+	for _, e := range expected {
+		tok := s.Scan()
+		if tok.Value != e.value || tok.Tok != e.tok || tok.Pos.String() != e.pos {
+			t.Errorf("expect %q (%v) at %s given %q (%v) at %v", e.value, e.tok, e.pos, tok.Value, tok.Tok, tok.Pos)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+// Package lint walks parsed instructions and reports suspicious code.
+// Every Diagnostic carries the source range of the offending instructions
+// and, where one exists, a Fix which can be applied with Apply.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/momaee/WL/parser"
+	"github.com/momaee/WL/token"
+)
+
+// names of the checks reported in Diagnostic.Check
+const (
+	CancellingPair  = "cancelling-pair"
+	DeadLoop        = "dead-loop"
+	StartLoop       = "start-loop"
+	UnbalancedLoop  = "unbalanced-loop"
+	UnreachableCode = "unreachable-code"
+)
+
+// Edit replaces the source between Pos and End with NewText.
+type Edit struct {
+	Pos     token.Pos
+	End     token.Pos
+	NewText string
+}
+
+// Fix is a set of edits which resolves a Diagnostic.
+type Fix struct {
+	Message string
+	Edits   []Edit
+}
+
+// Diagnostic is a single finding of the linter.
+// Fix is nil if there is no safe automatic fix.
+type Diagnostic struct {
+	Pos     token.Pos
+	End     token.Pos
+	Check   string
+	Message string
+	Fix     *Fix
+}
+
+// String returns the diagnostic in line:column: message (check) format.
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%v: %s (%s)", d.Pos, d.Message, d.Check)
+}
+
+// Lint runs all checks against inst and returns the diagnostics ordered by position.
+func Lint(inst []*parser.Inst) []Diagnostic {
+	l := &linter{inst: inst}
+	l.block(0, len(inst))
+	sort.SliceStable(l.diags, func(i, j int) bool {
+		return l.diags[i].Pos.Offset < l.diags[j].Pos.Offset
+	})
+	return l.diags
+}
+
+// linter keeps the instructions and the diagnostics found so far.
+type linter struct {
+	inst  []*parser.Inst
+	diags []Diagnostic
+}
+
+// block checks the instructions in [start, end), recursing into loops.
+// end is the index of the closing bracket of the enclosing loop or len(inst).
+func (l *linter) block(start, end int) {
+	for i := start; i < end; i++ {
+		in := l.inst[i]
+		switch in.T.Tok {
+		case token.PlusToken, token.MinusToken, token.LeftToken, token.RightToken:
+			if i+1 < end && cancels(in, l.inst[i+1]) {
+				l.cancellingPair(in, l.inst[i+1])
+				i++
+			}
+
+		case token.LeftBracketToken:
+			closeLoop := in.C
+			if closeLoop <= i || closeLoop >= len(l.inst) {
+				// not linked to its ], the parser has reported it
+				continue
+			}
+			dead := true // loops which never run are not checked any further
+			switch {
+			case i == 0:
+				l.removeLoop(i, StartLoop, "loop at the start of the program never runs, the cell is zero")
+			case l.inst[i-1].T.Tok == token.RightBracketToken:
+				l.removeLoop(i, DeadLoop, "loop never runs, the cell is zero after the previous loop")
+			default:
+				dead = false
+			}
+			if dead {
+				i = closeLoop
+				continue
+			}
+
+			if net, ok := l.net(i); ok && net != 0 {
+				l.report(Diagnostic{
+					Pos:     in.Pos,
+					End:     l.inst[closeLoop].End,
+					Check:   UnbalancedLoop,
+					Message: fmt.Sprintf("loop moves the pointer by %+d on every iteration", net),
+				})
+			}
+
+			if closeLoop == i+1 && closeLoop+1 < end {
+				l.unreachable(i, closeLoop+1, end)
+			}
+
+			l.block(i+1, closeLoop)
+			i = closeLoop
+		}
+	}
+}
+
+// cancellingPair reports two adjacent instructions which cancel each other out.
+// the fix keeps only the net effect of both.
+func (l *linter) cancellingPair(a, b *parser.Inst) {
+	net := a.C - b.C
+	fix := &Fix{
+		Message: "remove the cancelling operations",
+		Edits: []Edit{
+			{Pos: a.Pos, End: a.End},
+			{Pos: b.Pos, End: b.End},
+		},
+	}
+	if net > 0 {
+		fix.Edits[0].NewText = strings.Repeat(a.T.Value, net)
+	} else if net < 0 {
+		fix.Edits[1].NewText = strings.Repeat(b.T.Value, -net)
+	}
+	l.report(Diagnostic{
+		Pos:     a.Pos,
+		End:     b.End,
+		Check:   CancellingPair,
+		Message: fmt.Sprintf("%q followed by %q cancel each other out", a.T.Value, b.T.Value),
+		Fix:     fix,
+	})
+}
+
+// removeLoop reports the loop opened at i as never running.
+func (l *linter) removeLoop(i int, check, msg string) {
+	in := l.inst[i]
+	end := l.inst[in.C].End
+	l.report(Diagnostic{
+		Pos:     in.Pos,
+		End:     end,
+		Check:   check,
+		Message: msg,
+		Fix: &Fix{
+			Message: "remove the loop",
+			Edits:   []Edit{{Pos: in.Pos, End: end}},
+		},
+	})
+}
+
+// unreachable reports the instructions in [start, end) following the empty loop at i.
+// the code only runs if the loop is skipped, it is removed by the fix if the cell is known to be non-zero.
+func (l *linter) unreachable(i, start, end int) {
+	pos := l.inst[start].Pos
+	last := l.inst[end-1].End
+	d := Diagnostic{
+		Pos:     pos,
+		End:     last,
+		Check:   UnreachableCode,
+		Message: fmt.Sprintf("code after infinite loop [] at %v only runs if the loop is skipped", l.inst[i].Pos),
+	}
+	if l.nonZero(i) {
+		d.Message = fmt.Sprintf("code after infinite loop [] at %v never runs, the cell is not zero", l.inst[i].Pos)
+		d.Fix = &Fix{
+			Message: "remove the code after the infinite loop",
+			Edits:   []Edit{{Pos: pos, End: last}},
+		}
+	}
+	l.report(d)
+}
+
+// nonZero returns true if the cell is known to be non-zero before the instruction at i:
+// only '+' and '-' run since the start of the program or the end of a loop, where the cell is zero,
+// and they do not add up to a multiple of 256.
+func (l *linter) nonZero(i int) bool {
+	delta := 0
+	for j := i - 1; j >= 0; j-- {
+		switch in := l.inst[j]; in.T.Tok {
+		case token.PlusToken:
+			delta += in.C
+		case token.MinusToken:
+			delta -= in.C
+		case token.RightBracketToken:
+			return delta%256 != 0
+		default:
+			return false
+		}
+	}
+	return delta%256 != 0
+}
+
+// net returns the pointer movement of one iteration of the loop opened at i.
+// ok is false if the movement is unknown because a nested loop is unbalanced.
+func (l *linter) net(i int) (net int, ok bool) {
+	for j := i + 1; j < l.inst[i].C; j++ {
+		switch in := l.inst[j]; in.T.Tok {
+		case token.RightToken:
+			net += in.C
+		case token.LeftToken:
+			net -= in.C
+		case token.LeftBracketToken:
+			if n, ok := l.net(j); !ok || n != 0 {
+				return 0, false
+			}
+			j = in.C
+		}
+	}
+	return net, true
+}
+
+func (l *linter) report(d Diagnostic) {
+	l.diags = append(l.diags, d)
+}
+
+// cancels returns true if b undoes (part of) the effect of a.
+func cancels(a, b *parser.Inst) bool {
+	switch a.T.Tok {
+	case token.PlusToken:
+		return b.T.Tok == token.MinusToken
+	case token.MinusToken:
+		return b.T.Tok == token.PlusToken
+	case token.LeftToken:
+		return b.T.Tok == token.RightToken
+	case token.RightToken:
+		return b.T.Tok == token.LeftToken
+	}
+	return false
+}
+
+// Apply applies the fixes of diags to src and returns the result.
+// A fix which overlaps one applied before it is skipped;
+// running Lint and Apply again picks it up if it still applies.
+func Apply(src []byte, diags []Diagnostic) []byte {
+	var edits []Edit
+	for _, d := range diags {
+		if d.Fix == nil || overlaps(edits, d.Fix.Edits) {
+			continue
+		}
+		edits = append(edits, d.Fix.Edits...)
+	}
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].Pos.Offset < edits[j].Pos.Offset
+	})
+
+	var out []byte
+	last := 0
+	for _, e := range edits {
+		out = append(out, src[last:e.Pos.Offset]...)
+		out = append(out, e.NewText...)
+		last = e.End.Offset
+	}
+	return append(out, src[last:]...)
+}
+
+// overlaps returns true if any edit in b touches the range of an edit in a.
+func overlaps(a, b []Edit) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x.Pos.Offset < y.End.Offset && y.Pos.Offset < x.End.Offset {
+				return true
+			}
+		}
+	}
+	return false
+}
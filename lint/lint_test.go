@@ -0,0 +1,123 @@
+package lint_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/momaee/WL/lexer"
+	"github.com/momaee/WL/lint"
+	"github.com/momaee/WL/parser"
+	"github.com/momaee/WL/token"
+)
+
+func lintString(src string) []lint.Diagnostic {
+	p := parser.NewParser(lexer.NewScanner(strings.NewReader(src)))
+	return lint.Lint(p.Parse())
+}
+
+func TestLint_Checks(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		check string
+		pos   string
+	}{
+		{"plus minus", "+>++--", lint.CancellingPair, "1:3"},
+		{"left right", "+\n<<>", lint.CancellingPair, "2:1"},
+		{"dead loop", "+[-][>]", lint.DeadLoop, "1:5"},
+		{"start loop", "[comment]+", lint.StartLoop, "1:1"},
+		{"unbalanced loop", "+[->>]", lint.UnbalancedLoop, "1:2"},
+		{"unreachable code", "+[]>+", lint.UnreachableCode, "1:4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags := lintString(tt.src)
+			if len(diags) != 1 {
+				t.Fatalf("expected one diagnostic, got %v", diags)
+			}
+			if diags[0].Check != tt.check {
+				t.Errorf("expected check %s got %s", tt.check, diags[0].Check)
+			}
+			if diags[0].Pos.String() != tt.pos {
+				t.Errorf("expected position %s got %s", tt.pos, diags[0].Pos)
+			}
+		})
+	}
+}
+
+func TestLint_Clean(t *testing.T) {
+	src := "++++++++[>++++[>++>+++>+++>+<<<<-]>+>+>->>+[<]<-]>>.>---.+++++++..+++.>>.<-.<.+++.------.--------.>>+.>++."
+	for _, d := range lintString(src) {
+		if d.Check != lint.UnbalancedLoop {
+			t.Errorf("unexpected diagnostic %v", d)
+		}
+	}
+}
+
+func TestLint_Unreachable(t *testing.T) {
+	diags := lintString(",[].")
+	if len(diags) != 1 || diags[0].Check != lint.UnreachableCode || diags[0].Fix != nil {
+		t.Errorf("expected unreachable code without a fix got %v", diags)
+	}
+}
+
+func TestLint_Unbalanced(t *testing.T) {
+	// the parser reports and drops the unmatched brackets, the rest is linted as usual
+	tests := []struct {
+		src      string
+		expected []string
+	}{
+		{"+[", nil},
+		{"[[[[", nil},
+		{"[[-]>", []string{"1:2: loop at the start of the program never runs, the cell is zero (start-loop)"}},
+		{"+[[]>", []string{"1:5: code after infinite loop [] at 1:3 never runs, the cell is not zero (unreachable-code)"}},
+		{"]+-", []string{`1:2: "+" followed by "-" cancel each other out (cancelling-pair)`}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, d := range lintString(tt.src) {
+			got = append(got, d.String())
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("%q: expected %q got %q", tt.src, tt.expected, got)
+		}
+	}
+
+	// an instruction which is not linked to its ]
+	inst := []*parser.Inst{
+		{T: token.Builtin(token.PlusToken), C: 1},
+		{T: token.Builtin(token.LeftBracketToken), C: 0},
+		{T: token.Builtin(token.MinusToken), C: 1},
+	}
+	if diags := lint.Lint(inst); len(diags) != 0 {
+		t.Errorf("unexpected diagnostics %v", diags)
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{"+++-- x", "+ x"},
+		{"+--- x", "-- x"},
+		{">< x", " x"},
+		{"[start]+[-][dead]>", "+[-]>"},
+		{"+[>]<<<>>", "+[>]<"},
+		{"+[]>+", "+[]"},
+		{"+[-]+[].", "+[-]+[]"},
+		{",[].", ",[]."},
+		{"+>[].", "+>[]."},
+		{"++--[].", "[]."},
+		{"<+->+", "<>+"}, // '+' and '-' do nothing outside of the tape
+	}
+
+	for _, tt := range tests {
+		src := []byte(tt.src)
+		out := lint.Apply(src, lintString(tt.src))
+		if string(out) != tt.expected {
+			t.Errorf("apply %q: expected %q got %q", tt.src, tt.expected, out)
+		}
+	}
+}
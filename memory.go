@@ -0,0 +1,87 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/momaee/WL/parser"
+	"github.com/momaee/WL/token"
+)
+
+// DefaultCellSize is the number of bits of a cell, unless set by WithCellSize.
+const DefaultCellSize = 8
+
+// ErrTapeBounds is returned by Run if an instruction uses a cell outside of the tape, unless it wraps around, see WithWrappingTape.
+// the pointer itself may leave the tape and '+' and '-' do nothing there, the cell can not be used without failing,
+// so "<>" and "<+-" on the first cell behave like everywhere else and cancelling them is safe.
+var ErrTapeBounds = errors.New("pointer outside of the tape")
+
+// WithCellSize sets the number of bits of a cell, 8, 16 or 32.
+// '+' and '-' wrap around at the size of the cell.
+func WithCellSize(bits int) Option {
+	return func(b *brainFuck) {
+		b.cellSize = bits
+	}
+}
+
+// WithWrappingTape makes the pointer wrap around the ends of the tape of token.MemorySize cells,
+// '<' on the first cell moves to the last one.
+func WithWrappingTape() Option {
+	return func(b *brainFuck) {
+		b.wrapTape = true
+	}
+}
+
+// checkCellSize returns an error if the cell size is not supported.
+func (b *brainFuck) checkCellSize() error {
+	switch b.cellSize {
+	case 8, 16, 32:
+		return nil
+	}
+	return fmt.Errorf("unsupported cell size %d", b.cellSize)
+}
+
+// add adds c to the current cell, wrapping around at the cell size.
+// it does nothing outside of the tape.
+func (b *brainFuck) add(c int) {
+	if cur := b.cur(); cur < 0 || cur >= token.MemorySize {
+		return
+	}
+	b.memory.Cell[b.cur()] = b.wrap(b.memory.Cell[b.cur()] + c)
+}
+
+// wrap returns v wrapped around at the cell size.
+func (b *brainFuck) wrap(v int) int {
+	switch b.cellSize {
+	case 16:
+		return int(uint16(v))
+	case 32:
+		return int(uint32(v))
+	}
+	return int(uint8(v))
+}
+
+// checkTape fails if in uses a cell outside of the tape.
+// '<' and '>' only move the pointer, '+' and '-' are skipped by add unless they are replaced by an operator.
+func (b *brainFuck) checkTape(in *parser.Inst) error {
+	cur := b.cur()
+	if cur >= 0 && cur < token.MemorySize {
+		return nil
+	}
+	switch in.T.Tok {
+	case token.LeftToken, token.RightToken:
+		return nil
+	case token.PlusToken, token.MinusToken:
+		if _, ok := b.overrides[in.T.Tok]; !ok && in.T.ContextOperator == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("%v: cell %d: %w", in.Pos, cur, ErrTapeBounds)
+}
+
+// wrapCursor moves the pointer back onto the tape if it wraps around.
+func (b *brainFuck) wrapCursor() {
+	if cur := b.cur(); b.wrapTape && (cur < 0 || cur >= token.MemorySize) {
+		b.memory.Cursor = (cur%token.MemorySize + token.MemorySize) % token.MemorySize
+	}
+}
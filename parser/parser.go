@@ -10,17 +10,23 @@ import (
 
 // RuneParser will parse tokens and pack them in instructions
 // initial state of the RuneParser is Parse method
+// Err returns the first error found by Parse, like an unmatched bracket
 type RuneParser interface {
 	Parse() []*Inst
+	Err() error
 }
 
 // Inst is an abstraction for an operation which machine can understand
 // T is one single instruction
 // C is complementary information about instruction like position or counts of occurrence
 // Incase of opening loop, C is the index of the closing loop and vice versa
+// the same applies to the opening and closing parenthesis of a procedure
+// Pos and End are the source range of the instruction, including all folded tokens
 type Inst struct {
-	T *token.Token
-	C int
+	T   *token.Token
+	C   int
+	Pos token.Pos
+	End token.Pos
 }
 
 // parser builds AST (abstract structure tree).
@@ -36,6 +42,7 @@ type parser struct {
 		tokbufn bool         // whether the token buffer is in use.
 	}
 	stack stack.Stack
+	err   error
 }
 
 // NewParser creates new parser using given LexScanner.
@@ -43,30 +50,91 @@ func NewParser(l lexer.LexScanner) RuneParser {
 	return &parser{l: l}
 }
 
+// Parse reads tokens until the end of the input and returns the instructions.
+// whitespace and any other text which is not an operator is treated as a comment.
+// unmatched brackets are left out of the instructions and reported by Err.
 func (p *parser) Parse() []*Inst {
 	for {
 		tok := p.scan()
-		fmt.Println("tok:", tok)
-		if tok.Tok == token.IllegalToken {
-			break
-		}
+		switch tok.Tok {
+		case token.EOFToken:
+			if p.stack.Len() > 0 {
+				p.removeOpen()
+			}
+			return p.inst
 
-		for _, t := range token.AllTokens {
-			if tok == t {
-				if tok.Tok == token.LeftBracketToken {
-					openLoop := p.buildInst(tok, 0)
-					p.stack.Push(openLoop)
-				} else if tok.Tok == token.RightBracketToken {
-					openLoop := p.stack.Pop().(int)
-					closeLoop := p.buildInst(tok, openLoop)
-					p.inst[openLoop].C = closeLoop
-				} else {
-					p.addInst(tok)
-				}
+		case token.IllegalToken, token.WhitespaceToken:
+			continue
+
+		case token.LeftBracketToken, token.ProcStartToken:
+			openLoop := p.buildInst(tok, 0)
+			p.stack.Push(openLoop)
+
+		case token.RightBracketToken, token.ProcEndToken:
+			if p.stack.Len() == 0 {
+				p.fail(fmt.Errorf("%v: unmatched %s", tok.Pos, tok.Value))
+				continue
+			}
+			openLoop := p.stack.Pop().(int)
+			if !matches(p.inst[openLoop].T.Tok, tok.Tok) {
+				p.fail(fmt.Errorf("%v: %s does not match %s at %v", tok.Pos, tok.Value, p.inst[openLoop].T.Value, p.inst[openLoop].Pos))
 			}
+			closeLoop := p.buildInst(tok, openLoop)
+			p.inst[openLoop].C = closeLoop
+
+		case token.CallToken, token.ForkToken:
+			// every call returns before the next one and every fork starts a thread, so they are not folded
+			p.buildInst(tok, 1)
+
+		default:
+			p.addInst(tok)
 		}
 	}
-	return p.inst
+}
+
+// removeOpen reports the brackets left open at the end of the input and removes them from the instructions,
+// the links of the other brackets are moved to the new indexes.
+func (p *parser) removeOpen() {
+	open := map[int]bool{}
+	for p.stack.Len() > 0 {
+		i := p.stack.Pop().(int)
+		open[i] = true
+		p.fail(fmt.Errorf("%v: unmatched %s", p.inst[i].Pos, p.inst[i].T.Value))
+	}
+
+	index := make([]int, len(p.inst))
+	inst := p.inst[:0]
+	for i, in := range p.inst {
+		index[i] = len(inst)
+		if !open[i] {
+			inst = append(inst, in)
+		}
+	}
+	for _, in := range inst {
+		switch in.T.Tok {
+		case token.LeftBracketToken, token.RightBracketToken, token.ProcStartToken, token.ProcEndToken:
+			in.C = index[in.C]
+		}
+	}
+	p.inst = inst
+}
+
+// Err returns the first error found by Parse.
+func (p *parser) Err() error {
+	return p.err
+}
+
+// fail records err unless there is an earlier error.
+func (p *parser) fail(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+// matches returns true if closing is the counterpart of opening.
+func matches(opening, closing token.Type) bool {
+	return (opening == token.LeftBracketToken && closing == token.RightBracketToken) ||
+		(opening == token.ProcStartToken && closing == token.ProcEndToken)
 }
 
 // scan returns next token unit.
@@ -93,23 +161,29 @@ func (p *parser) unscan() {
 func (p *parser) addInst(t *token.Token) int {
 	// token occurrence count
 	c := 1
+	end := t.End
 	for {
 		next := p.scan()
-		if next.Tok != t.Tok {
+		if next.Tok != t.Tok || next.Value != t.Value {
 			p.unscan()
 			break
 		}
+		end = next.End
 		c++
 	}
-	return p.buildInst(t, c)
+	i := p.buildInst(t, c)
+	p.inst[i].End = end
+	return i
 }
 
 // buildInst creates a instruction from the given literals.
 func (p *parser) buildInst(t *token.Token, c int) int {
 	// build instruction
 	inst := &Inst{
-		T: t,
-		C: c,
+		T:   t,
+		C:   c,
+		Pos: t.Pos,
+		End: t.End,
 	}
 	// add inst to instruction list
 	p.inst = append(p.inst, inst)
@@ -91,3 +91,78 @@ func Test_MoveBetweenCells(t *testing.T) {
 	}
 
 }
+
+func TestParser_Err(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"+[-]", ""},
+		{"+]-", "1:2: unmatched ]"},
+		{"+[\n[-]", "1:2: unmatched ["},
+	}
+
+	for _, tt := range tests {
+		p := parser.NewParser(lexer.NewScanner(strings.NewReader(tt.input)))
+		p.Parse()
+		err := ""
+		if p.Err() != nil {
+			err = p.Err().Error()
+		}
+		if err != tt.err {
+			t.Errorf("%q: expected error %q got %q", tt.input, tt.err, err)
+		}
+	}
+}
+
+func TestParser_UnmatchedOpen(t *testing.T) {
+	// the outer [ is left out, the inner loop is linked at its new indexes
+	p := parser.NewParser(lexer.NewScanner(strings.NewReader("+[[-]>")))
+	instructions := p.Parse()
+	if p.Err() == nil || p.Err().Error() != "1:2: unmatched [" {
+		t.Errorf("expected unmatched [ got %v", p.Err())
+	}
+
+	expected := []struct {
+		value string
+		c     int
+	}{{"+", 1}, {"[", 3}, {"-", 1}, {"]", 1}, {">", 1}}
+	if len(instructions) != len(expected) {
+		t.Fatalf("wrong length, expected %d got %d", len(expected), len(instructions))
+	}
+	for i, e := range expected {
+		if instructions[i].T.Value != e.value || instructions[i].C != e.c {
+			t.Errorf("incorrect instruction %d. expected %s %d got %s %d", i, e.value, e.c, instructions[i].T.Value, instructions[i].C)
+		}
+	}
+}
+
+func TestParser_Procedures(t *testing.T) {
+	symbols := token.Symbols()
+	for r, tok := range token.ProcedureTokens {
+		symbols[string(r)] = tok
+	}
+	p := parser.NewParser(lexer.NewScanner(strings.NewReader("+(-[-]):: ([)]"), lexer.WithSymbols(symbols)))
+	instructions := p.Parse()
+
+	expected := []*parser.Inst{
+		{T: &token.Token{Tok: token.PlusToken, Value: "+"}, C: 1},
+		{T: &token.Token{Tok: token.ProcStartToken, Value: "("}, C: 6},
+		{T: &token.Token{Tok: token.MinusToken, Value: "-"}, C: 1},
+		{T: &token.Token{Tok: token.LeftBracketToken, Value: "["}, C: 5},
+		{T: &token.Token{Tok: token.MinusToken, Value: "-"}, C: 1},
+		{T: &token.Token{Tok: token.RightBracketToken, Value: "]"}, C: 3},
+		{T: &token.Token{Tok: token.ProcEndToken, Value: ")"}, C: 1},
+		{T: &token.Token{Tok: token.CallToken, Value: ":"}, C: 1},
+		{T: &token.Token{Tok: token.CallToken, Value: ":"}, C: 1},
+	}
+	for i, v := range expected {
+		if v.T.Tok != instructions[i].T.Tok || v.C != instructions[i].C || v.T.Value != instructions[i].T.Value {
+			t.Errorf("incorrect instruction. expected %+v got %+v", *v, *instructions[i])
+		}
+	}
+
+	if p.Err() == nil || p.Err().Error() != "1:13: ) does not match [ at 1:12" {
+		t.Errorf("expected mismatch error got %v", p.Err())
+	}
+}
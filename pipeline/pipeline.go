@@ -0,0 +1,239 @@
+// Package pipeline runs Brainfuck programs concurrently, the output of each one is the input of the next.
+//
+// Every stage runs in its own goroutine, the stages are connected by bounded channels.
+// The first failing stage cancels the others, Run reports which stage it was.
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	interpreter "github.com/momaee/WL"
+)
+
+// DefaultBuffer is the capacity of the channels between the stages, unless set in Pipeline.
+const DefaultBuffer = 16
+
+// Stage is a program of a pipeline.
+type Stage struct {
+	Name    string
+	Code    string
+	Options []interpreter.Option
+}
+
+// Stats are the statistics of a stage after Run.
+type Stats struct {
+	Name     string
+	Steps    int
+	BytesIn  int64
+	BytesOut int64
+	Duration time.Duration
+}
+
+// StageError is returned by Run if a stage fails.
+type StageError struct {
+	Stage int
+	Name  string
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("stage %d (%s): %v", e.Stage, e.Name, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// Pipeline connects the stages in order.
+// Buffer is the number of writes a stage can make before the next stage reads them.
+type Pipeline struct {
+	Stages []Stage
+	Buffer int
+}
+
+// New returns a Pipeline of stages.
+func New(stages ...Stage) *Pipeline {
+	return &Pipeline{Stages: stages, Buffer: DefaultBuffer}
+}
+
+// Run runs the stages until all of them have ended, reading in and writing the output of the last stage to out.
+// a stage ends when its program ends or when the next stage has ended.
+// err is a *StageError if a stage fails, or the error of ctx if it is cancelled.
+func (p *Pipeline) Run(ctx context.Context, in io.Reader, out io.Writer) ([]Stats, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if in == nil {
+		in = strings.NewReader("")
+	}
+
+	n := len(p.Stages)
+	stats := make([]Stats, n)
+	links := make([]*link, n+1)
+	for i := 1; i < n; i++ {
+		links[i] = newLink(p.Buffer)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		once sync.Once
+		err  error
+	)
+	for i, stage := range p.Stages {
+		wg.Add(1)
+		go func(i int, stage Stage) {
+			defer wg.Done()
+			stats[i].Name = stage.Name
+			if serr := p.run(ctx, stage, links[i], links[i+1], in, out, &stats[i]); serr != nil {
+				once.Do(func() {
+					err = &StageError{Stage: i, Name: stage.Name, Err: serr}
+					cancel()
+				})
+			}
+		}(i, stage)
+	}
+	wg.Wait()
+
+	if err == nil {
+		err = ctx.Err()
+	}
+	return stats, err
+}
+
+// run runs one stage reading from prev or in and writing to next or out,
+// prev is nil for the first stage and next for the last one.
+func (p *Pipeline) run(ctx context.Context, stage Stage, prev, next *link, in io.Reader, out io.Writer, stats *Stats) error {
+	// the stage stops without an error once the next stage has ended
+	sctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	r := &countReader{r: in}
+	if prev != nil {
+		r.r = &linkReader{l: prev, ctx: sctx}
+		defer prev.close()
+	}
+	w := &countWriter{w: out}
+	if next != nil {
+		w.w = &linkWriter{l: next, ctx: sctx, stop: stop}
+		defer close(next.data)
+	}
+
+	start := time.Now()
+	opts := append(stage.Options[:len(stage.Options):len(stage.Options)], interpreter.WithContext(sctx))
+	bfm := interpreter.NewInterpreter(r, w, strings.NewReader(stage.Code), opts...)
+	err := bfm.Run()
+
+	stats.Steps = bfm.Steps()
+	stats.BytesIn = r.n
+	stats.BytesOut = w.n
+	stats.Duration = time.Since(start)
+
+	if ctx.Err() != nil {
+		// cancelled by another stage or the caller
+		return nil
+	}
+	if next != nil && next.closed() {
+		return nil
+	}
+	if err == io.EOF {
+		// the program has read past the end of its input
+		return nil
+	}
+	return err
+}
+
+// link is the channel between two stages, done is closed when the reading stage has ended.
+type link struct {
+	data chan []byte
+	done chan struct{}
+	once sync.Once
+}
+
+func newLink(size int) *link {
+	if size <= 0 {
+		size = DefaultBuffer
+	}
+	return &link{data: make(chan []byte, size), done: make(chan struct{})}
+}
+
+func (l *link) close() {
+	l.once.Do(func() { close(l.done) })
+}
+
+func (l *link) closed() bool {
+	select {
+	case <-l.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// linkReader reads the data sent to a link.
+type linkReader struct {
+	l   *link
+	ctx context.Context
+	buf []byte
+}
+
+func (r *linkReader) Read(p []byte) (int, error) {
+	if len(r.buf) == 0 {
+		select {
+		case data, ok := <-r.l.data:
+			if !ok {
+				return 0, io.EOF
+			}
+			r.buf = data
+		case <-r.ctx.Done():
+			return 0, r.ctx.Err()
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// linkWriter sends the written data to a link, it stops the writing stage once the reading one has ended.
+type linkWriter struct {
+	l    *link
+	ctx  context.Context
+	stop context.CancelFunc
+}
+
+func (w *linkWriter) Write(p []byte) (int, error) {
+	select {
+	case w.l.data <- append([]byte(nil), p...):
+		return len(p), nil
+	case <-w.l.done:
+		w.stop()
+		return 0, io.ErrClosedPipe
+	case <-w.ctx.Done():
+		return 0, w.ctx.Err()
+	}
+}
+
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,83 @@
+package pipeline_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	interpreter "github.com/momaee/WL"
+	"github.com/momaee/WL/pipeline"
+)
+
+// cat copies its input up to the zero byte, upper makes it upper case.
+// both print the zero byte at the end, so the next stage ends too.
+var (
+	cat   = pipeline.Stage{Name: "cat", Code: ",[.,]."}
+	upper = pipeline.Stage{Name: "upper", Code: ",[" + strings.Repeat("-", 32) + ".,]."}
+)
+
+func TestPipeline_Run(t *testing.T) {
+	p := pipeline.New(cat, upper)
+
+	var out bytes.Buffer
+	stats, err := p.Run(context.Background(), strings.NewReader("hello\x00"), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "HELLO\x00" {
+		t.Errorf("expected %q got %q", "HELLO\x00", out.String())
+	}
+
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 stats got %d", len(stats))
+	}
+	if stats[0].Name != "cat" || stats[0].BytesIn != 6 || stats[0].BytesOut != 6 || stats[0].Steps == 0 {
+		t.Errorf("unexpected stats of cat %+v", stats[0])
+	}
+	if stats[1].Name != "upper" || stats[1].BytesIn != 6 || stats[1].BytesOut != 6 {
+		t.Errorf("unexpected stats of upper %+v", stats[1])
+	}
+}
+
+func TestPipeline_StageError(t *testing.T) {
+	// the second stage fails while the first one runs forever
+	forever := pipeline.Stage{Name: "forever", Code: "+[.]"}
+	broken := pipeline.Stage{Name: "broken", Code: ",:", Options: []interpreter.Option{interpreter.WithProcedures()}}
+
+	_, err := pipeline.New(forever, broken).Run(context.Background(), nil, new(bytes.Buffer))
+	var serr *pipeline.StageError
+	if !errors.As(err, &serr) {
+		t.Fatalf("expected stage error got %v", err)
+	}
+	if serr.Stage != 1 || serr.Name != "broken" || !errors.Is(err, interpreter.ErrUndefinedProcedure) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestPipeline_EarlyEnd(t *testing.T) {
+	// the last stage reads one byte only, the first one stops with it
+	forever := pipeline.Stage{Name: "forever", Code: strings.Repeat("+", 65) + "[.]"}
+	first := pipeline.Stage{Name: "first", Code: ",."}
+
+	var out bytes.Buffer
+	_, err := pipeline.New(forever, first).Run(context.Background(), nil, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "A" {
+		t.Errorf("expected A got %q", out.String())
+	}
+}
+
+func TestPipeline_Cancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := pipeline.New(pipeline.Stage{Name: "loop", Code: "+[]"}, cat).Run(ctx, nil, new(bytes.Buffer))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded got %v", err)
+	}
+}
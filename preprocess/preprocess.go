@@ -0,0 +1,447 @@
+// Package preprocess expands macros in Brainfuck sources before they are scanned.
+//
+//	#define clear [-]
+//	#define move(n) {>}*n
+//	#include "lib/print.b"
+//	#clear #move(3) {+}*10
+//
+// #define and #include start a line and end with it.
+// Parameters are replaced in the body of a macro as whole words,
+// the path of an include is relative to the including file.
+// Arguments are separated by ';', as ',' is the read command: #copy(1; 2) or #twice(,.).
+// {body}*count repeats the body, {+}*10 is the same as ++++++++++,
+// braces which are not followed by *count are kept like comments.
+// Everything else is kept as it is, so comments are passed on to the lexer.
+// The output comes with a SourceMap leading every byte back to the file and position it came from.
+package preprocess
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/momaee/WL/token"
+)
+
+// limits of the preprocessor
+const (
+	// MaxDepth is the maximum nesting of macro expansions and includes.
+	MaxDepth = 64
+	// MaxCount is the maximum count of a repetition.
+	MaxCount = 1 << 20
+	// MaxLength is the maximum number of characters of an expansion.
+	MaxLength = 1 << 20
+)
+
+// Location is a position in a source file.
+type Location struct {
+	File string
+	Pos  token.Pos
+}
+
+// String returns the location in file:line:column format.
+func (l Location) String() string {
+	return fmt.Sprintf("%s:%v", l.File, l.Pos)
+}
+
+// Error is a preprocessing error at a location in the sources.
+type Error struct {
+	Loc Location
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v: %s", e.Loc, e.Msg)
+}
+
+// SourceMap leads the bytes of the preprocessed code back to the sources.
+// bytes of an expanded macro belong to the invocation, except for the arguments.
+type SourceMap struct {
+	locs []Location
+}
+
+// Lookup returns the source location of the byte at offset of the preprocessed code.
+func (m *SourceMap) Lookup(offset int) Location {
+	if offset < 0 || len(m.locs) == 0 {
+		return Location{}
+	}
+	if offset >= len(m.locs) {
+		offset = len(m.locs) - 1
+	}
+	return m.locs[offset]
+}
+
+// Result is the preprocessed code with its source map.
+type Result struct {
+	Code string
+	Map  *SourceMap
+}
+
+// Process preprocesses the file name of fsys, the included files are read from fsys as well.
+// err is an *Error if the sources are not valid.
+func Process(fsys fs.FS, name string) (*Result, error) {
+	p := &processor{fsys: fsys, macros: map[string]*macro{}}
+	text, err := p.include(name, Location{File: name, Pos: token.Pos{Line: 1, Column: 1}})
+	if err != nil {
+		return nil, err
+	}
+
+	var code strings.Builder
+	m := &SourceMap{}
+	buf := make([]byte, utf8.UTFMax)
+	for _, u := range text {
+		n := utf8.EncodeRune(buf, u.r)
+		code.Write(buf[:n])
+		for i := 0; i < n; i++ {
+			m.locs = append(m.locs, u.loc)
+		}
+	}
+	return &Result{Code: code.String(), Map: m}, nil
+}
+
+// unit is a rune of the text with its source location.
+type unit struct {
+	r   rune
+	loc Location
+}
+
+type macro struct {
+	params []string
+	body   []unit
+}
+
+// processor keeps the macros defined so far and the files being included.
+type processor struct {
+	fsys   fs.FS
+	macros map[string]*macro
+	files  []string
+	depth  int
+}
+
+func fail(loc Location, format string, args ...interface{}) error {
+	return &Error{Loc: loc, Msg: fmt.Sprintf(format, args...)}
+}
+
+// include reads and expands the file name, loc is the include directive.
+func (p *processor) include(name string, loc Location) ([]unit, error) {
+	for _, f := range p.files {
+		if f == name {
+			return nil, fail(loc, "%s includes itself", name)
+		}
+	}
+	if len(p.files) >= MaxDepth {
+		return nil, fail(loc, "includes nested too deep")
+	}
+	src, err := fs.ReadFile(p.fsys, name)
+	if err != nil {
+		return nil, fail(loc, "%v", err)
+	}
+
+	p.files = append(p.files, name)
+	defer func() { p.files = p.files[:len(p.files)-1] }()
+	return p.expand(locate(name, string(src)))
+}
+
+// locate returns the runes of src with their positions.
+func locate(file, src string) []unit {
+	text := make([]unit, 0, len(src))
+	pos := token.Pos{Line: 1, Column: 1}
+	for i, r := range src {
+		pos.Offset = i
+		text = append(text, unit{r: r, loc: Location{File: file, Pos: pos}})
+		if r == '\n' {
+			pos.Line++
+			pos.Column = 1
+		} else {
+			pos.Column++
+		}
+	}
+	return text
+}
+
+// expand returns text with the directives, invocations and repetitions expanded.
+func (p *processor) expand(text []unit) ([]unit, error) {
+	var out []unit
+	lineStart := true
+	for i := 0; i < len(text); {
+		u := text[i]
+		switch {
+		case u.r == '#':
+			name, j := ident(text, i+1)
+			if name == "" {
+				out = append(out, u)
+				i++
+				lineStart = false
+				continue
+			}
+			if lineStart && (name == "define" || name == "include") {
+				end := lineEnd(text, j)
+				var err error
+				if name == "define" {
+					err = p.define(text[j:end], u.loc)
+				} else {
+					var included []unit
+					if included, err = p.includeDirective(text[j:end], u.loc); err == nil {
+						out = append(out, included...)
+					}
+				}
+				if err != nil {
+					return nil, err
+				}
+				i = end
+				break
+			}
+			expanded, next, err := p.invoke(text, name, j, u.loc)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, expanded...)
+			i = next
+
+		case u.r == '{':
+			expanded, next, err := p.repeat(text, i, MaxLength-len(out))
+			if err != nil {
+				return nil, err
+			}
+			if next < 0 {
+				out = append(out, u)
+				i++
+				break
+			}
+			out = append(out, expanded...)
+			i = next
+
+		default:
+			out = append(out, u)
+			i++
+		}
+		if len(out) > MaxLength {
+			return nil, fail(u.loc, "expansion is longer than %d characters", MaxLength)
+		}
+
+		if u.r == '\n' {
+			lineStart = true
+		} else if !unicode.IsSpace(u.r) {
+			lineStart = false
+		}
+	}
+	return out, nil
+}
+
+// define adds the macro defined by the rest of a #define line.
+func (p *processor) define(line []unit, loc Location) error {
+	line = trim(line)
+	name, i := ident(line, 0)
+	if name == "" {
+		return fail(loc, "expected macro name")
+	}
+	if _, ok := p.macros[name]; ok || name == "define" || name == "include" {
+		return fail(loc, "macro %s already defined", name)
+	}
+
+	m := &macro{}
+	if i < len(line) && line[i].r == '(' {
+		args, next, err := arguments(line, i)
+		if err != nil {
+			return err
+		}
+		for _, a := range args {
+			param := string(runes(a))
+			if s, n := ident(a, 0); s == "" || n != len(a) {
+				return fail(loc, "invalid parameter %q of macro %s", param, name)
+			}
+			m.params = append(m.params, param)
+		}
+		i = next
+	}
+	m.body = trim(line[i:])
+	p.macros[name] = m
+	return nil
+}
+
+// includeDirective expands the file named by the rest of an #include line.
+func (p *processor) includeDirective(line []unit, loc Location) ([]unit, error) {
+	s := strings.TrimSpace(string(runes(line)))
+	name, err := strconv.Unquote(s)
+	if err != nil || !strings.HasPrefix(s, `"`) {
+		return nil, fail(loc, "expected quoted file name, found %q", s)
+	}
+	if !path.IsAbs(name) && len(p.files) > 0 {
+		name = path.Join(path.Dir(p.files[len(p.files)-1]), name)
+	}
+	return p.include(path.Clean(strings.TrimPrefix(name, "/")), loc)
+}
+
+// invoke expands the macro name, its arguments start at text[i] if it has parameters.
+// it returns the expansion and the index after the invocation.
+func (p *processor) invoke(text []unit, name string, i int, loc Location) ([]unit, int, error) {
+	m, ok := p.macros[name]
+	if !ok {
+		return nil, 0, fail(loc, "undefined macro %s", name)
+	}
+
+	var args [][]unit
+	if len(m.params) > 0 {
+		if i >= len(text) || text[i].r != '(' {
+			return nil, 0, fail(loc, "macro %s expects %d arguments", name, len(m.params))
+		}
+		var err error
+		if args, i, err = arguments(text, i); err != nil {
+			return nil, 0, err
+		}
+		if len(args) != len(m.params) {
+			return nil, 0, fail(loc, "macro %s expects %d arguments, found %d", name, len(m.params), len(args))
+		}
+	}
+
+	// the body belongs to the invocation, the arguments keep their own locations
+	var body []unit
+	for j := 0; j < len(m.body); {
+		word, next := ident(m.body, j)
+		if word == "" {
+			body = append(body, unit{r: m.body[j].r, loc: loc})
+			j++
+			continue
+		}
+		if k := index(m.params, word); k >= 0 {
+			body = append(body, args[k]...)
+		} else {
+			for _, u := range m.body[j:next] {
+				body = append(body, unit{r: u.r, loc: loc})
+			}
+		}
+		j = next
+	}
+
+	if p.depth >= MaxDepth {
+		return nil, 0, fail(loc, "macro %s nested too deep", name)
+	}
+	p.depth++
+	defer func() { p.depth-- }()
+	expanded, err := p.expand(body)
+	return expanded, i, err
+}
+
+// repeat expands {body}*count starting at text[i], the expansion may not be longer than limit.
+// it returns the expansion and the index after the count, -1 if the brace is not a repetition.
+func (p *processor) repeat(text []unit, i, limit int) ([]unit, int, error) {
+	depth := 0
+	end := -1
+	for j := i; j < len(text) && end < 0; j++ {
+		switch text[j].r {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				end = j
+			}
+		}
+	}
+	if end < 0 || end+1 >= len(text) || text[end+1].r != '*' {
+		return nil, -1, nil
+	}
+	j := end + 2
+	for j < len(text) && text[j].r >= '0' && text[j].r <= '9' {
+		j++
+	}
+	digits := string(runes(text[end+2 : j]))
+	if digits == "" {
+		return nil, 0, fail(text[end].loc, "expected count after }*")
+	}
+	count, err := strconv.Atoi(digits)
+	if err != nil || count > MaxCount {
+		return nil, 0, fail(text[end].loc, "count %s is larger than %d", digits, MaxCount)
+	}
+
+	body, err := p.expand(text[i+1 : end])
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(body)*count > limit {
+		return nil, 0, fail(text[end].loc, "expansion is longer than %d characters", MaxLength)
+	}
+	out := make([]unit, 0, len(body)*count)
+	for k := 0; k < count; k++ {
+		out = append(out, body...)
+	}
+	return out, j, nil
+}
+
+// arguments returns the semicolon separated arguments in parentheses starting at text[i],
+// and the index after the closing parenthesis. arguments may contain balanced parentheses.
+func arguments(text []unit, i int) ([][]unit, int, error) {
+	var args [][]unit
+	depth := 0
+	start := i + 1
+	for j := i; j < len(text); j++ {
+		switch text[j].r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				if a := trim(text[start:j]); len(a) > 0 || len(args) > 0 {
+					args = append(args, a)
+				}
+				return args, j + 1, nil
+			}
+		case ';':
+			if depth == 1 {
+				args = append(args, trim(text[start:j]))
+				start = j + 1
+			}
+		case '\n':
+			return nil, 0, fail(text[i].loc, "unmatched (")
+		}
+	}
+	return nil, 0, fail(text[i].loc, "unmatched (")
+}
+
+// ident returns the identifier starting at text[i] and the index after it.
+func ident(text []unit, i int) (string, int) {
+	j := i
+	for j < len(text) && (text[j].r == '_' || unicode.IsLetter(text[j].r) || (j > i && unicode.IsDigit(text[j].r))) {
+		j++
+	}
+	return string(runes(text[i:j])), j
+}
+
+// lineEnd returns the index of the newline ending the line of text[i], len(text) if there is none.
+func lineEnd(text []unit, i int) int {
+	for i < len(text) && text[i].r != '\n' {
+		i++
+	}
+	return i
+}
+
+func trim(text []unit) []unit {
+	for len(text) > 0 && unicode.IsSpace(text[0].r) {
+		text = text[1:]
+	}
+	for len(text) > 0 && unicode.IsSpace(text[len(text)-1].r) {
+		text = text[:len(text)-1]
+	}
+	return text
+}
+
+func runes(text []unit) []rune {
+	rs := make([]rune, len(text))
+	for i, u := range text {
+		rs[i] = u.r
+	}
+	return rs
+}
+
+func index(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
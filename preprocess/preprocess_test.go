@@ -0,0 +1,108 @@
+package preprocess_test
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/momaee/WL/preprocess"
+)
+
+func TestProcess(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.b": {Data: []byte(`#define clear [-]
+#define move(n) {>}*n
+#define copy(from; to) #move(from)[-#move(to)+]
+#include "lib/print.b"
+#clear #move(3) {+}*10
+{{+}*2>}*2 #copy(1; 2) #print #twice(-)
+`)},
+		"lib/print.b": {Data: []byte(`#define twice(x) x x
+#include "../common.b"
+#define print . #clear`)},
+		"common.b": {Data: []byte("; common")},
+	}
+
+	res, err := preprocess.Process(fsys, "main.b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "\n\n\n\n; common\n\n[-] >>> ++++++++++\n++>++> >[->>+] . [-] - -\n"
+	if res.Code != expected {
+		t.Errorf("expected %q got %q", expected, res.Code)
+	}
+
+	tests := []struct {
+		offset   int
+		expected string
+	}{
+		{4, "common.b:1:1"},
+		{14, "main.b:5:1"},  // the body of clear belongs to the invocation
+		{27, "main.b:5:18"}, // inside the repetition
+		{41, "main.b:6:12"},
+		{54, "main.b:6:38"}, // the argument of twice keeps its location
+	}
+	for _, tt := range tests {
+		if loc := res.Map.Lookup(tt.offset); loc.String() != tt.expected {
+			t.Errorf("offset %d (%q): expected %s got %s", tt.offset, res.Code[tt.offset], tt.expected, loc)
+		}
+	}
+}
+
+func TestProcess_Text(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{"#define twice(x) x x\n#twice(,.)", "\n,. ,."},
+		{"#define add(a; b) a>b\n#add(,; {+}*2)", "\n,>++"},
+		{"{ comment } {+}*2", "{ comment } ++"},
+		{"{ a {-}*2 }", "{ a -- }"},
+		{"+} {+", "+} {+"},
+		{"{+} *2", "{+} *2"},
+	}
+
+	for _, tt := range tests {
+		res, err := preprocess.Process(fstest.MapFS{"main.b": {Data: []byte(tt.src)}}, "main.b")
+		if err != nil {
+			t.Errorf("%q: %v", tt.src, err)
+			continue
+		}
+		if res.Code != tt.expected {
+			t.Errorf("%q: expected %q got %q", tt.src, tt.expected, res.Code)
+		}
+	}
+}
+
+func TestProcess_Errors(t *testing.T) {
+	tests := []struct {
+		src string
+		err string
+	}{
+		{"+#nope", "main.b:1:2: undefined macro nope"},
+		{"#define m(a) a\n#m", "main.b:2:1: macro m expects 1 arguments"},
+		{"#define m(a) a\n#m(1; 2)", "main.b:2:1: macro m expects 1 arguments, found 2"},
+		{"#define m #m\n#m", "main.b:2:1: macro m nested too deep"},
+		{"{+}*", "main.b:1:3: expected count after }*"},
+		{"#include \"main.b\"", "main.b:1:1: main.b includes itself"},
+		{"\n #include missing.b", `main.b:2:2: expected quoted file name, found "missing.b"`},
+		{"#define m(1) +", `main.b:1:1: invalid parameter "1" of macro m`},
+		{"{+}*9223372036854775807", "main.b:1:3: count 9223372036854775807 is larger than 1048576"},
+		{"{+}*99999999999999999999", "main.b:1:3: count 99999999999999999999 is larger than 1048576"},
+		{"{{+}*100000}*100000", "main.b:1:12: expansion is longer than 1048576 characters"},
+		{"{{+}*100000}*10{+}*100000", "main.b:1:18: expansion is longer than 1048576 characters"},
+		{"#define a {+}*300000\n#define b #a#a#a#a\n#b", "main.b:3:1: expansion is longer than 1048576 characters"},
+	}
+
+	for _, tt := range tests {
+		_, err := preprocess.Process(fstest.MapFS{"main.b": {Data: []byte(tt.src)}}, "main.b")
+		var perr *preprocess.Error
+		if !errors.As(err, &perr) {
+			t.Errorf("%q: expected preprocess error, got %v", tt.src, err)
+			continue
+		}
+		if err.Error() != tt.err {
+			t.Errorf("%q: expected %q got %q", tt.src, tt.err, err.Error())
+		}
+	}
+}
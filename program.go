@@ -0,0 +1,46 @@
+package interpreter
+
+import (
+	"errors"
+	"io"
+
+	"github.com/momaee/WL/parser"
+)
+
+// ErrStepLimit is returned by Run if the program executes more instructions than allowed by WithMaxSteps.
+var ErrStepLimit = errors.New("step limit exceeded")
+
+// WithMaxSteps makes Run fail with ErrStepLimit after n instructions, 0 means no limit.
+func WithMaxSteps(n int) Option {
+	return func(b *brainFuck) {
+		b.maxSteps = n
+	}
+}
+
+// Program is parsed code, ready to be run by many interpreters at once.
+type Program struct {
+	inst []*parser.Inst
+	opts []Option
+}
+
+// Compile parses code once, opts select the language like for NewInterpreter.
+func Compile(code io.Reader, opts ...Option) (*Program, error) {
+	b := NewInterpreter(nil, nil, code, append(opts[:len(opts):len(opts)], WithBufferSize(0))...).(*brainFuck)
+	inst, err := b.parse()
+	if err != nil {
+		return nil, err
+	}
+	if inst == nil {
+		inst = []*parser.Inst{}
+	}
+	return &Program{inst: inst, opts: opts}, nil
+}
+
+// NewInterpreter returns an interpreter running the program,
+// opts are applied after the ones given to Compile.
+func (p *Program) NewInterpreter(i io.Reader, w io.Writer, opts ...Option) Interpreter {
+	all := append(p.opts[:len(p.opts):len(p.opts)], opts...)
+	b := NewInterpreter(i, w, nil, all...).(*brainFuck)
+	b.inst = p.inst
+	return b
+}
@@ -0,0 +1,73 @@
+package interpreter
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/momaee/WL/parser"
+)
+
+// Reader runs a program only as far as needed to fill the buffers passed to Read,
+// the output of the program is the content of the Reader.
+// Readers can be chained, the input of a program can be the Reader of another one.
+type Reader struct {
+	b   *brainFuck
+	out bytes.Buffer
+	err error
+}
+
+// NewReader returns a Reader of the output of code run with input.
+// the output is not buffered, WithBufferSize in opts is ignored.
+// Brainfork threads are not supported.
+func NewReader(input io.Reader, code io.Reader, opts ...Option) *Reader {
+	r := &Reader{}
+	opts = append(opts[:len(opts):len(opts)], WithBufferSize(0))
+	r.b = NewInterpreter(input, &r.out, code, opts...).(*brainFuck)
+	return r
+}
+
+// Read executes the program until it has printed something or ended.
+// it returns io.EOF once the program has ended and all of its output is read.
+func (r *Reader) Read(p []byte) (int, error) {
+	for r.out.Len() == 0 && r.err == nil {
+		r.err = r.b.next()
+	}
+	if r.out.Len() > 0 {
+		return r.out.Read(p)
+	}
+	return 0, r.err
+}
+
+// next executes the next instruction, the code is parsed before the first one.
+// it returns io.EOF once the program has ended without an error.
+func (b *brainFuck) next() error {
+	if b.inst == nil {
+		if b.forks {
+			return errors.New("threads can not be run step by step")
+		}
+		inst, err := b.parse()
+		if err != nil {
+			b.err = err
+			return err
+		}
+		if inst == nil {
+			inst = []*parser.Inst{}
+		}
+		b.inst = inst
+	}
+
+	if b.ip >= len(b.inst) {
+		b.flushBits()
+		if b.err != nil && b.err != io.EOF {
+			return b.err
+		}
+		return io.EOF
+	}
+	if err := b.step(b.inst[b.ip]); err != nil {
+		b.err = err
+		return err
+	}
+	b.ip++
+	return nil
+}
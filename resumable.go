@@ -0,0 +1,121 @@
+package interpreter
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// State tells why Resumable.Run returned.
+type State int
+
+const (
+	// Done means the program has ended.
+	Done State = iota
+	// NeedInput means the program waits for input given with Feed.
+	NeedInput
+	// OutputReady means the program has printed something, it is returned by Output.
+	OutputReady
+)
+
+func (s State) String() string {
+	switch s {
+	case NeedInput:
+		return "need input"
+	case OutputReady:
+		return "output ready"
+	}
+	return "done"
+}
+
+// errNeedInput is returned by the input of a Resumable when all fed bytes are read.
+var errNeedInput = errors.New("need input")
+
+// Resumable runs a program without ever blocking on input,
+// Run returns when the program needs more input and continues where it stopped on the next call.
+// an instruction waiting for input is executed again once there is more input,
+// so a ContextOperator reading input should do so before it changes anything.
+type Resumable struct {
+	b   *brainFuck
+	in  resumableInput
+	out bytes.Buffer
+}
+
+// NewResumable returns a Resumable running code, the input is given with Feed.
+// the output is not buffered, WithBufferSize in opts is ignored.
+// Brainfork threads are not supported.
+func NewResumable(code io.Reader, opts ...Option) *Resumable {
+	r := &Resumable{}
+	opts = append(opts[:len(opts):len(opts)], WithBufferSize(0))
+	r.b = NewInterpreter(&r.in, &r.out, code, opts...).(*brainFuck)
+	return r
+}
+
+// Run executes the program until it prints something, needs input or ends.
+// err != nil if the program fails, the state is Done then.
+func (r *Resumable) Run() (State, error) {
+	for {
+		ip, steps, prev := r.b.ip, r.b.steps, r.b.err
+		r.in.mark = r.in.pos
+		err := r.b.next()
+		if errors.Is(err, errNeedInput) || errors.Is(r.b.err, errNeedInput) {
+			r.b.ip, r.b.steps, r.b.err = ip, steps, prev
+			r.in.pos = r.in.mark
+			return NeedInput, nil
+		}
+		if err == io.EOF {
+			return Done, nil
+		}
+		if err != nil {
+			return Done, err
+		}
+		if r.out.Len() > 0 {
+			return OutputReady, nil
+		}
+	}
+}
+
+// Feed adds p to the input of the program.
+func (r *Resumable) Feed(p []byte) {
+	// the bytes read so far are not needed anymore
+	r.in.data = append(r.in.data[:0], r.in.data[r.in.pos:]...)
+	r.in.pos = 0
+	r.in.data = append(r.in.data, p...)
+}
+
+// CloseInput ends the input, the program reads io.EOF once it has read all fed bytes.
+func (r *Resumable) CloseInput() {
+	r.in.closed = true
+}
+
+// Output returns the output printed since the last call.
+func (r *Resumable) Output() []byte {
+	p := append([]byte(nil), r.out.Bytes()...)
+	r.out.Reset()
+	return p
+}
+
+// GetValueInMemory returns the value of the cell at position.
+func (r *Resumable) GetValueInMemory(position int) int {
+	return r.b.GetValueInMemory(position)
+}
+
+// resumableInput reads the fed bytes, mark is the position before the running instruction.
+type resumableInput struct {
+	data   []byte
+	pos    int
+	mark   int
+	closed bool
+}
+
+func (in *resumableInput) Read(p []byte) (int, error) {
+	if in.pos >= len(in.data) {
+		if in.closed {
+			return 0, io.EOF
+		}
+		return 0, errNeedInput
+	}
+	n := copy(p, in.data[in.pos:])
+	in.pos += n
+	return n, nil
+}
@@ -0,0 +1,147 @@
+package token
+
+import (
+	"image"
+	"image/color"
+	"strings"
+)
+
+// BitTape is a tape of bits which grows in both directions as cells are set.
+// Cursor is the position of the current bit, positions may be negative.
+type BitTape struct {
+	Cursor int
+	words  []uint64
+	origin int // bit index of position 0 in words
+}
+
+// Get returns the bit at position i.
+func (t *BitTape) Get(i int) bool {
+	j := i + t.origin
+	if j < 0 || j >= len(t.words)*64 {
+		return false
+	}
+	return t.words[j/64]&(1<<uint(j%64)) != 0
+}
+
+// Set sets the bit at position i to v.
+func (t *BitTape) Set(i int, v bool) {
+	j := t.index(i)
+	if v {
+		t.words[j/64] |= 1 << uint(j%64)
+	} else {
+		t.words[j/64] &^= 1 << uint(j%64)
+	}
+}
+
+// Flip inverts the bit at position i.
+func (t *BitTape) Flip(i int) {
+	j := t.index(i)
+	t.words[j/64] ^= 1 << uint(j%64)
+}
+
+// index returns the bit index of position i in words, growing the tape if needed.
+func (t *BitTape) index(i int) int {
+	j := i + t.origin
+	if j < 0 {
+		n := (-j + 63) / 64
+		t.words = append(make([]uint64, n), t.words...)
+		t.origin += n * 64
+		j += n * 64
+	}
+	if n := j/64 + 1 - len(t.words); n > 0 {
+		t.words = append(t.words, make([]uint64, n)...)
+	}
+	return j
+}
+
+// Grid is a toroidal grid of bits, the pointer wraps around the edges.
+// X and Y are the position of the pointer, Y grows downwards.
+// Grid implements image.Image, set bits are black.
+type Grid struct {
+	X, Y   int
+	width  int
+	height int
+	cells  []bool
+}
+
+// NewGrid returns a grid of width x height cleared bits.
+// both must be positive, otherwise the grid has no cells and the interpreter rejects it.
+func NewGrid(width, height int) *Grid {
+	g := &Grid{width: width, height: height}
+	if width > 0 && height > 0 {
+		g.cells = make([]bool, width*height)
+	}
+	return g
+}
+
+// Width returns the number of columns of the grid.
+func (g *Grid) Width() int {
+	return g.width
+}
+
+// Height returns the number of rows of the grid.
+func (g *Grid) Height() int {
+	return g.height
+}
+
+// Get returns the bit at column x of row y.
+func (g *Grid) Get(x, y int) bool {
+	return g.cells[g.index(x, y)]
+}
+
+// Flip inverts the bit at column x of row y.
+func (g *Grid) Flip(x, y int) {
+	i := g.index(x, y)
+	g.cells[i] = !g.cells[i]
+}
+
+// Move moves the pointer by dx columns and dy rows.
+func (g *Grid) Move(dx, dy int) {
+	g.X = mod(g.X+dx, g.width)
+	g.Y = mod(g.Y+dy, g.height)
+}
+
+// String returns the rows of the grid as lines of 0 and 1.
+func (g *Grid) String() string {
+	var b strings.Builder
+	for y := 0; y < g.height; y++ {
+		if y > 0 {
+			b.WriteByte('\n')
+		}
+		for x := 0; x < g.width; x++ {
+			if g.Get(x, y) {
+				b.WriteByte('1')
+			} else {
+				b.WriteByte('0')
+			}
+		}
+	}
+	return b.String()
+}
+
+// ColorModel is part of image.Image.
+func (g *Grid) ColorModel() color.Model {
+	return color.GrayModel
+}
+
+// Bounds is part of image.Image.
+func (g *Grid) Bounds() image.Rectangle {
+	return image.Rect(0, 0, g.width, g.height)
+}
+
+// At is part of image.Image.
+func (g *Grid) At(x, y int) color.Color {
+	if g.Get(x, y) {
+		return color.Gray{Y: 0}
+	}
+	return color.Gray{Y: 255}
+}
+
+// index returns the index of the cell at column x of row y, both wrap around.
+func (g *Grid) index(x, y int) int {
+	return mod(y, g.height)*g.width + mod(x, g.width)
+}
+
+func mod(a, n int) int {
+	return ((a % n) + n) % n
+}
@@ -1,6 +1,10 @@
 package token
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
 
 const (
 	IllegalToken      Type = iota
@@ -14,6 +18,14 @@ const (
 	RightBracketToken      // ]
 	WhitespaceToken
 	UserDefinedToken
+	EOFToken
+	ProcStartToken // (
+	ProcEndToken   // )
+	CallToken      // :
+	ForkToken      // Y
+	FlipToken      // + in Boolfuck, * in Smallfuck and Paintfuck
+	UpToken        // n in Paintfuck
+	DownToken      // s in Paintfuck
 )
 
 // Memory capacity
@@ -25,6 +37,19 @@ var EOF = rune(-1)
 // Type represents a lexical token type.
 type Type int
 
+// Pos describes a position in the source code.
+// Offset is counted in bytes, Line and Column start at 1.
+type Pos struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// String returns the position in line:column format.
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
 // Token represents a lexical tokens.
 type Token struct {
 	// the type of token.
@@ -34,6 +59,13 @@ type Token struct {
 	Value string
 
 	Operator Operator
+
+	// ContextOperator is used instead of Operator by operators added with AddContextOperator.
+	ContextOperator ContextOperator
+
+	// Pos and End are the source range of the token, set by the lexer.
+	Pos Pos
+	End Pos
 }
 
 type Memory struct {
@@ -45,31 +77,109 @@ type Memory struct {
 // Incase of opening loop, C is the index of the closing loop and vice versa
 type Operator func(c int, memory *Memory)
 
+// Context is the state of the interpreter handed to a ContextOperator.
+// IP is the index of the executing instruction, the interpreter continues with the instruction after IP,
+// so an operator can jump by changing it.
+// Steps is the number of instructions executed so far, including this one.
+// Len is the number of instructions, an operator ends the program by setting IP to Len.
+// CellSize is the number of bits of a cell.
+type Context struct {
+	Memory   *Memory
+	Reader   io.Reader
+	Writer   io.Writer
+	IP       int
+	Steps    int
+	Len      int
+	CellSize int
+}
+
+// ContextOperator is an operator with access to the I/O and the control flow of the interpreter.
+// C has the same meaning as for Operator.
+// A returned error halts the interpreter.
+type ContextOperator func(c int, ctx *Context) error
+
+// builtins are the eight commands of Brainfuck, not affected by AddOperator and RemoveOperator.
+var builtins = map[Type]*Token{
+	LeftToken:         {Tok: LeftToken, Value: "<", Operator: seekBwd},
+	RightToken:        {Tok: RightToken, Value: ">", Operator: seekFwd},
+	PlusToken:         {Tok: PlusToken, Value: "+", Operator: inc},
+	MinusToken:        {Tok: MinusToken, Value: "-", Operator: dec},
+	PrintToken:        {Tok: PrintToken, Value: "."},
+	ReadToken:         {Tok: ReadToken, Value: ","},
+	LeftBracketToken:  {Tok: LeftBracketToken, Value: "["},
+	RightBracketToken: {Tok: RightBracketToken, Value: "]"},
+}
+
 var (
-		AllTokens = map[rune]*Token{
-		'<': {Tok: LeftToken, Value: "<", Operator: seekBwd},
-		'>': {Tok: RightToken, Value: ">", Operator: seekFwd},
-		'+': {Tok: PlusToken, Value: "+", Operator: inc},
-		'-': {Tok: MinusToken, Value: "-", Operator: dec},
-		'.': {Tok: PrintToken, Value: "."},
-		',': {Tok: ReadToken, Value: ","},
-		'[': {Tok: LeftBracketToken, Value: "["},
-		']': {Tok: RightBracketToken, Value: "]"},
+	AllTokens = map[rune]*Token{
+		'<': builtins[LeftToken],
+		'>': builtins[RightToken],
+		'+': builtins[PlusToken],
+		'-': builtins[MinusToken],
+		'.': builtins[PrintToken],
+		',': builtins[ReadToken],
+		'[': builtins[LeftBracketToken],
+		']': builtins[RightBracketToken],
+	}
+
+	// ProcedureTokens are the commands of the pbrain extension,
+	// they are only recognized by interpreters with procedures enabled.
+	ProcedureTokens = map[rune]*Token{
+		'(': {Tok: ProcStartToken, Value: "("},
+		')': {Tok: ProcEndToken, Value: ")"},
+		':': {Tok: CallToken, Value: ":"},
 	}
+
+	// ForkTokens are the commands of Brainfork,
+	// they are only recognized by interpreters with forks enabled.
+	ForkTokens = map[rune]*Token{
+		'Y': {Tok: ForkToken, Value: "Y"},
+	}
+
+	// BoolfuckTokens are the commands of Boolfuck, which works on a tape of bits.
+	BoolfuckTokens = map[rune]*Token{
+		'<': builtins[LeftToken],
+		'>': builtins[RightToken],
+		'[': builtins[LeftBracketToken],
+		']': builtins[RightBracketToken],
+		',': builtins[ReadToken],
+		';': {Tok: PrintToken, Value: ";"},
+		'+': {Tok: FlipToken, Value: "+"},
+	}
+
+	// SmallfuckTokens are the commands of Smallfuck, Boolfuck without I/O on a finite tape.
+	SmallfuckTokens = map[rune]*Token{
+		'<': builtins[LeftToken],
+		'>': builtins[RightToken],
+		'[': builtins[LeftBracketToken],
+		']': builtins[RightBracketToken],
+		'*': {Tok: FlipToken, Value: "*"},
+	}
+
+	// PaintfuckTokens are the commands of Paintfuck, which works on a grid of bits.
+	PaintfuckTokens = map[rune]*Token{
+		'n': {Tok: UpToken, Value: "n"},
+		's': {Tok: DownToken, Value: "s"},
+		'e': {Tok: RightToken, Value: "e"},
+		'w': {Tok: LeftToken, Value: "w"},
+		'[': builtins[LeftBracketToken],
+		']': builtins[RightBracketToken],
+		'*': {Tok: FlipToken, Value: "*"},
+	}
+
+	// Keywords holds the operators whose symbol is longer than one rune, like "**" or "swap".
+	// the lexer prefers the longest symbol matching the input.
+	Keywords = map[string]*Token{}
 )
 
-// dec method decrements the value of the current Cell in memory by v.
+// dec method decrements the value of the current Cell in memory by v, wrapping around at 256.
 var dec Operator = func(c int, memory *Memory) {
-	if memory.Cell[memory.Cursor]-c >= 0 {
-		memory.Cell[memory.Cursor] -= c
-	} else {
-		memory.Cell[memory.Cursor] = 256 + memory.Cell[memory.Cursor] - c
-	}
+	memory.Cell[memory.Cursor] = int(uint8(memory.Cell[memory.Cursor] - c))
 }
 
-// inc method increments the value of the current Cell in memory by v.
+// inc method increments the value of the current Cell in memory by v, wrapping around at 256.
 var inc Operator = func(c int, memory *Memory) {
-	memory.Cell[memory.Cursor] = (memory.Cell[memory.Cursor] + c) % 255
+	memory.Cell[memory.Cursor] = int(uint8(memory.Cell[memory.Cursor] + c))
 }
 
 // seekFwd method moves the cursor in the memory forward by c.
@@ -92,6 +202,57 @@ func AddOperator(symbol rune, operator Operator) error {
 	return nil
 }
 
+// AddContextOperator registers op for symbol, like AddOperator.
+func AddContextOperator(symbol rune, op ContextOperator) error {
+	if _, ok := AllTokens[symbol]; ok {
+		return fmt.Errorf("symbol %v already exists", symbol)
+	}
+	AllTokens[symbol] = &Token{Tok: UserDefinedToken, ContextOperator: op, Value: string(symbol)}
+	return nil
+}
+
+// AddKeywordOperator registers operator for a symbol of one or more runes.
+func AddKeywordOperator(symbol string, operator Operator) error {
+	return addKeyword(symbol, &Token{Tok: UserDefinedToken, Operator: operator, Value: symbol})
+}
+
+// AddKeywordContextOperator registers op for a symbol of one or more runes.
+func AddKeywordContextOperator(symbol string, op ContextOperator) error {
+	return addKeyword(symbol, &Token{Tok: UserDefinedToken, ContextOperator: op, Value: symbol})
+}
+
+// addKeyword stores single rune symbols in AllTokens and longer ones in Keywords.
+func addKeyword(symbol string, tok *Token) error {
+	switch utf8.RuneCountInString(symbol) {
+	case 0:
+		return fmt.Errorf("empty symbol")
+	case 1:
+		r, _ := utf8.DecodeRuneInString(symbol)
+		if _, ok := AllTokens[r]; ok {
+			return fmt.Errorf("symbol %q already exists", symbol)
+		}
+		AllTokens[r] = tok
+	default:
+		if _, ok := Keywords[symbol]; ok {
+			return fmt.Errorf("symbol %q already exists", symbol)
+		}
+		Keywords[symbol] = tok
+	}
+	return nil
+}
+
+// RemoveKeyword removes the operator registered for symbol.
+func RemoveKeyword(symbol string) error {
+	if r, size := utf8.DecodeRuneInString(symbol); size > 0 && size == len(symbol) {
+		return RemoveOperator(r)
+	}
+	if _, ok := Keywords[symbol]; !ok {
+		return fmt.Errorf("symbol %q does not exist", symbol)
+	}
+	delete(Keywords, symbol)
+	return nil
+}
+
 func RemoveOperator(symbol rune) error {
 	if _, ok := AllTokens[symbol]; !ok {
 		return fmt.Errorf("symbol %v does not exist", symbol)
@@ -100,6 +261,39 @@ func RemoveOperator(symbol rune) error {
 	return nil
 }
 
+// Symbols returns a copy of all registered symbols, from AllTokens and Keywords.
+func Symbols() map[string]*Token {
+	symbols := make(map[string]*Token, len(AllTokens)+len(Keywords))
+	for r, tok := range AllTokens {
+		symbols[string(r)] = tok
+	}
+	for symbol, tok := range Keywords {
+		symbols[symbol] = tok
+	}
+	return symbols
+}
+
+// Builtin returns the original token of a Brainfuck command, nil if t is not one.
+func Builtin(t Type) *Token {
+	return builtins[t]
+}
+
+// BuiltinSymbol returns the original token of the Brainfuck command symbol, nil if symbol is not one.
+// unlike AllTokens it is not changed by AddOperator and RemoveOperator.
+func BuiltinSymbol(symbol rune) *Token {
+	for _, tok := range builtins {
+		if tok.Value == string(symbol) {
+			return tok
+		}
+	}
+	return nil
+}
+
+// IsBuiltin returns true for the eight commands of Brainfuck.
+func (t *Token) IsBuiltin() bool {
+	return t.Tok >= LeftToken && t.Tok <= RightBracketToken
+}
+
 func (t *Token) HasOperator() bool {
 	return t.Operator != nil
 }
@@ -0,0 +1,199 @@
+// Package trace records the execution of Brainfuck programs for post-mortem analysis.
+//
+// Writer writes every executed instruction as a line of JSON, Loops summarizes the executions of the loops
+// in the Chrome trace event format, which can be opened in chrome://tracing or https://ui.perfetto.dev.
+// Both are interpreter.Tracer:
+//
+//	w := trace.NewWriter(f, trace.Sample(100))
+//	loops := trace.NewLoops()
+//	bfm := interpreter.NewInterpreter(in, out, code, interpreter.WithTracer(w), interpreter.WithTracer(loops))
+//	err := bfm.Run()
+//	err = w.Flush()
+//	err = loops.WriteChrome(f)
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	interpreter "github.com/momaee/WL"
+)
+
+// Writer writes the traced instructions to an io.Writer as JSON lines.
+type Writer struct {
+	enc   *json.Encoder
+	every int
+	n     int
+	ring  []interpreter.TraceEvent
+	next  int
+	full  bool
+	err   error
+}
+
+// Option configures a Writer.
+type Option func(*Writer)
+
+// Sample makes the Writer keep only every n-th instruction, starting with the first one.
+func Sample(n int) Option {
+	return func(w *Writer) {
+		w.every = n
+	}
+}
+
+// Ring makes the Writer keep only the last n instructions, they are written by Flush.
+func Ring(n int) Option {
+	return func(w *Writer) {
+		w.ring = make([]interpreter.TraceEvent, n)
+	}
+}
+
+// NewWriter returns a Writer writing to w.
+func NewWriter(w io.Writer, opts ...Option) *Writer {
+	tw := &Writer{enc: json.NewEncoder(w), every: 1}
+	tw.enc.SetEscapeHTML(false)
+	for _, opt := range opts {
+		opt(tw)
+	}
+	return tw
+}
+
+// Trace writes e, or keeps it in the ring buffer.
+func (w *Writer) Trace(e interpreter.TraceEvent) {
+	w.n++
+	if w.every > 1 && (w.n-1)%w.every != 0 {
+		return
+	}
+	if len(w.ring) > 0 {
+		w.ring[w.next] = e
+		w.next = (w.next + 1) % len(w.ring)
+		w.full = w.full || w.next == 0
+		return
+	}
+	w.write(e)
+}
+
+// Flush writes the instructions of the ring buffer, oldest first, and empties it.
+// it returns the first error of the underlying writer.
+func (w *Writer) Flush() error {
+	if w.full {
+		for _, e := range w.ring[w.next:] {
+			w.write(e)
+		}
+	}
+	for _, e := range w.ring[:w.next] {
+		w.write(e)
+	}
+	w.next, w.full = 0, false
+	return w.err
+}
+
+func (w *Writer) write(e interpreter.TraceEvent) {
+	if w.err == nil {
+		w.err = w.enc.Encode(e)
+	}
+}
+
+// Loops summarizes the executions of the loops of a program,
+// one execution lasts from entering the loop until leaving it, with all of its iterations.
+// steps are used as the time, one step is one microsecond in the trace viewer.
+type Loops struct {
+	open []*execution
+	done []*execution
+	last int
+}
+
+// execution is one execution of the loop starting at the instruction ip.
+type execution struct {
+	ip         int
+	end        int
+	start      int
+	steps      int
+	iterations int
+}
+
+// NewLoops returns an empty loop summary.
+func NewLoops() *Loops {
+	return &Loops{}
+}
+
+// Trace follows the brackets in e.
+func (l *Loops) Trace(e interpreter.TraceEvent) {
+	l.last = e.Step
+	switch e.Op {
+	case "[":
+		if e.Next == e.IP+1 {
+			l.open = append(l.open, &execution{ip: e.IP, end: e.Count, start: e.Step, iterations: 1})
+		}
+	case "]":
+		if e.Next == e.Count+1 {
+			if top := l.top(e.Count); top != nil {
+				top.iterations++
+			}
+			return
+		}
+		// leaving the loop, loops left without their ] are closed as well
+		for i := len(l.open) - 1; i >= 0; i-- {
+			if l.open[i].ip == e.Count {
+				for len(l.open) > i {
+					l.finish(e.Step)
+				}
+				break
+			}
+		}
+	}
+}
+
+// top returns the innermost open execution of the loop starting at ip.
+func (l *Loops) top(ip int) *execution {
+	for i := len(l.open) - 1; i >= 0; i-- {
+		if l.open[i].ip == ip {
+			return l.open[i]
+		}
+	}
+	return nil
+}
+
+// finish ends the innermost open execution at step.
+func (l *Loops) finish(step int) {
+	x := l.open[len(l.open)-1]
+	l.open = l.open[:len(l.open)-1]
+	x.steps = step - x.start + 1
+	l.done = append(l.done, x)
+}
+
+// chromeEvent is a complete event of the Chrome trace event format.
+type chromeEvent struct {
+	Name string                 `json:"name"`
+	Ph   string                 `json:"ph"`
+	Ts   int                    `json:"ts"`
+	Dur  int                    `json:"dur"`
+	Pid  int                    `json:"pid"`
+	Tid  int                    `json:"tid"`
+	Args map[string]interface{} `json:"args"`
+}
+
+// WriteChrome writes the loop executions to w in the Chrome trace event format.
+// loops which are still running are ended at the last traced step.
+func (l *Loops) WriteChrome(w io.Writer) error {
+	for len(l.open) > 0 {
+		l.finish(l.last)
+	}
+	sort.SliceStable(l.done, func(i, j int) bool { return l.done[i].start < l.done[j].start })
+	events := make([]chromeEvent, 0, len(l.done))
+	for _, x := range l.done {
+		events = append(events, chromeEvent{
+			Name: fmt.Sprintf("loop %d-%d", x.ip, x.end),
+			Ph:   "X",
+			Ts:   x.start,
+			Dur:  x.steps,
+			Pid:  1,
+			Tid:  1,
+			Args: map[string]interface{}{"ip": x.ip, "iterations": x.iterations},
+		})
+	}
+	return json.NewEncoder(w).Encode(struct {
+		TraceEvents []chromeEvent `json:"traceEvents"`
+	}{events})
+}
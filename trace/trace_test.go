@@ -0,0 +1,140 @@
+package trace_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	interpreter "github.com/momaee/WL"
+	"github.com/momaee/WL/trace"
+)
+
+func run(t *testing.T, code string, tracers ...interpreter.Tracer) {
+	t.Helper()
+	var opts []interpreter.Option
+	for _, tr := range tracers {
+		opts = append(opts, interpreter.WithTracer(tr))
+	}
+	if err := interpreter.NewInterpreter(nil, new(bytes.Buffer), strings.NewReader(code), opts...).Run(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func events(t *testing.T, data []byte) []interpreter.TraceEvent {
+	t.Helper()
+	var evs []interpreter.TraceEvent
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		var e interpreter.TraceEvent
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		evs = append(evs, e)
+	}
+	return evs
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := trace.NewWriter(&buf)
+	run(t, "++>+", w)
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"step":1,"ip":0,"op":"+","count":2,"cursor":0,"before":0,"after":2,"next":1}
+{"step":2,"ip":1,"op":">","count":1,"cursor":0,"before":2,"after":2,"next":2}
+{"step":3,"ip":2,"op":"+","count":1,"cursor":1,"before":0,"after":1,"next":3}
+`
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func TestWriter_SampleRing(t *testing.T) {
+	// the loop runs 3 times, 1 + 1 + 3*5 = 17 instructions
+	code := "+++[->+<]"
+
+	var buf bytes.Buffer
+	w := trace.NewWriter(&buf, trace.Sample(3))
+	run(t, code, w)
+	_ = w.Flush()
+	var steps []int
+	for _, e := range events(t, buf.Bytes()) {
+		steps = append(steps, e.Step)
+	}
+	if len(steps) != 6 || steps[0] != 1 || steps[1] != 4 || steps[5] != 16 {
+		t.Errorf("unexpected sampled steps %v", steps)
+	}
+
+	buf.Reset()
+	w = trace.NewWriter(&buf, trace.Ring(4))
+	run(t, code, w)
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing to be written before Flush got %q", buf.String())
+	}
+	_ = w.Flush()
+	evs := events(t, buf.Bytes())
+	if len(evs) != 4 || evs[0].Step != 14 || evs[3].Step != 17 || evs[3].Op != "]" || evs[3].Next != 7 {
+		t.Errorf("unexpected ring %+v", evs)
+	}
+}
+
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestWriter_Error(t *testing.T) {
+	w := trace.NewWriter(failWriter{})
+	run(t, "+", w)
+	if err := w.Flush(); err == nil || err.Error() != "disk full" {
+		t.Errorf("expected the error of the writer got %v", err)
+	}
+}
+
+func TestLoops(t *testing.T) {
+	// the outer loop runs twice, the inner one three times per iteration, the last loop is skipped
+	loops := trace.NewLoops()
+	run(t, "++[>+++[>+<-]<-][]", loops)
+
+	var buf bytes.Buffer
+	if err := loops.WriteChrome(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		TraceEvents []struct {
+			Name string         `json:"name"`
+			Ph   string         `json:"ph"`
+			Ts   int            `json:"ts"`
+			Dur  int            `json:"dur"`
+			Args map[string]int `json:"args"`
+		} `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	evs := out.TraceEvents
+	if len(evs) != 3 {
+		t.Fatalf("expected 3 loop executions got %d: %s", len(evs), buf.String())
+	}
+	outer, first, second := evs[0], evs[1], evs[2]
+	if outer.Name != "loop 1-12" || outer.Ph != "X" || outer.Ts != 2 || outer.Args["iterations"] != 2 {
+		t.Errorf("unexpected outer loop %+v", outer)
+	}
+	if first.Name != "loop 4-9" || first.Args["iterations"] != 3 || first.Ts < outer.Ts || first.Ts+first.Dur > outer.Ts+outer.Dur {
+		t.Errorf("unexpected inner loop %+v in %+v", first, outer)
+	}
+	if second.Ts <= first.Ts+first.Dur {
+		t.Errorf("expected the second execution after the first one got %+v", second)
+	}
+	// '[' and two iterations of "> +++ inner < - ]", the inner loop takes 1 + 3*5 steps
+	if outer.Dur != 1+2*(2+16+3) || first.Dur != 16 {
+		t.Errorf("unexpected end of the outer loop %+v", outer)
+	}
+}
@@ -0,0 +1,79 @@
+package interpreter
+
+import (
+	"github.com/momaee/WL/parser"
+	"github.com/momaee/WL/token"
+)
+
+// TraceEvent describes an executed instruction.
+// Count is the number of folded commands, for brackets the index of the matching one.
+// Cursor is the position of the pointer before the instruction, Before and After are the values of that cell,
+// in Paintfuck the cells of the grid are numbered row by row.
+// Next is the index of the instruction executed next, Err is set if the instruction failed.
+type TraceEvent struct {
+	Step   int    `json:"step"`
+	IP     int    `json:"ip"`
+	Op     string `json:"op"`
+	Count  int    `json:"count"`
+	Cursor int    `json:"cursor"`
+	Before int    `json:"before"`
+	After  int    `json:"after"`
+	Next   int    `json:"next"`
+	Err    string `json:"error,omitempty"`
+}
+
+// Tracer is called after every executed instruction, see WithTracer.
+// Trace is never called concurrently, also not by threads run with the Goroutines scheduler.
+type Tracer interface {
+	Trace(e TraceEvent)
+}
+
+// WithTracer adds a tracer to the interpreter, it can be given more than once.
+// interpreters without tracers are not slowed down.
+func WithTracer(t Tracer) Option {
+	return func(b *brainFuck) {
+		b.tracers = append(b.tracers, t)
+	}
+}
+
+// traced executes in and passes it to the tracers.
+func (b *brainFuck) traced(in *parser.Inst) error {
+	cur := b.cursor()
+	e := TraceEvent{
+		Step:   b.steps,
+		IP:     b.ip,
+		Op:     in.T.Value,
+		Count:  in.C,
+		Cursor: cur,
+		Before: b.cell(cur),
+	}
+	err := b.exec(in)
+	e.After = b.cell(cur)
+	e.Next = b.ip + 1
+	if err != nil {
+		e.Err = err.Error()
+	}
+	for _, t := range b.tracers {
+		t.Trace(e)
+	}
+	return err
+}
+
+// cursor returns the position of the pointer, see GetValueInMemory.
+func (b *brainFuck) cursor() int {
+	switch b.mode {
+	case Boolfuck, Smallfuck:
+		return b.bits.Cursor
+	case Paintfuck:
+		return b.grid.Y*b.grid.Width() + b.grid.X
+	}
+	return b.memory.Cursor
+}
+
+// cell returns the value of the cell at position, 0 outside of the tape.
+func (b *brainFuck) cell(position int) int {
+	if b.mode == Brainfuck && (position < 0 || position >= token.MemorySize) {
+		return 0
+	}
+	return b.GetValueInMemory(position)
+}